@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/telegram"
+)
+
+// decode prints the contents of hex telegrams given as arguments or on stdin and returns the exit code.
+func decode(args []string) int {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "usage: mgw-wmbus-dc decode [flags] [telegram...]")
+		_, _ = fmt.Fprintln(flags.Output(), "telegrams are read from stdin, one per line, if none are given")
+		flags.PrintDefaults()
+	}
+	keyHex := flags.String("key", "", "AES key as 32 hex characters, looked up in the meters of -config if empty")
+	confPath := flags.String("config", "", "path to config JSON file with meter keys")
+	format := flags.String("format", "json", "output format, json or table")
+	_ = flags.Parse(args)
+
+	if *format != "json" && *format != "table" {
+		_, _ = fmt.Fprintf(os.Stderr, "format: unknown format %q, expected json or table\n", *format)
+		return 2
+	}
+	key, err := hex.DecodeString(*keyHex)
+	if err != nil || (len(key) != 0 && len(key) != 16) {
+		_, _ = fmt.Fprintln(os.Stderr, "key: must be 32 hex characters")
+		return 2
+	}
+	var cfg *config.Config
+	if len(key) == 0 && *confPath != "" {
+		cfg, err = config.New(*confPath)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	telegrams := flags.Args()
+	if len(telegrams) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				telegrams = append(telegrams, line)
+			}
+		}
+	}
+
+	ec := 0
+	for _, s := range telegrams {
+		b, err := telegram.ParseHex(s)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", s, err)
+			ec = 1
+			continue
+		}
+		k := key
+		if len(k) == 0 && cfg != nil {
+			k = meterKey(cfg, b)
+		}
+		t, err := telegram.Decode(b, k)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", s, err)
+			ec = 1
+			continue
+		}
+		if *format == "table" {
+			printTelegramTable(os.Stdout, t)
+			continue
+		}
+		j, _ := json.MarshalIndent(t, "", "  ")
+		_, _ = fmt.Println(string(j))
+	}
+	return ec
+}
+
+// meterKey returns the key configured for the meter of a telegram, nil if there is none. The transport layer id
+// of long headers takes precedence over the link layer id, which may be that of a repeater.
+func meterKey(cfg *config.Config, b []byte) []byte {
+	ids, err := telegram.MeterIds(b)
+	if err != nil {
+		return nil
+	}
+	slices.Reverse(ids)
+	for _, id := range ids {
+		m, ok := cfg.Meter(id)
+		if !ok {
+			continue
+		}
+		key, err := hex.DecodeString(m.Key.Value())
+		if err != nil {
+			return nil
+		}
+		return key
+	}
+	return nil
+}
+
+func printTelegramTable(out io.Writer, t *telegram.Telegram) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	l := t.Link
+	_, _ = fmt.Fprintf(w, "link layer\tc 0x%02X (%s), manufacturer %s, id %s, version 0x%02X, type 0x%02X (%s), ci 0x%02X\n",
+		l.C, l.Function, l.Manufacturer, l.Id, l.Version, l.DeviceType, l.Media, l.CI)
+	if e := t.ELL; e != nil {
+		_, _ = fmt.Fprintf(w, "extended link layer\tcc 0x%02X, acc 0x%02X, session %d, encrypted %t\n", e.CC, e.ACC, e.SessionNo, e.Encrypted)
+	}
+	if tl := t.Transport; tl != nil {
+		_, _ = fmt.Fprintf(w, "transport layer\t%s header, acc 0x%02X, status 0x%02X, config 0x%04X", tl.Header, tl.ACC, tl.Status, tl.Config)
+		if tl.Header == "long" {
+			_, _ = fmt.Fprintf(w, ", manufacturer %s, id %s, version 0x%02X, type 0x%02X (%s)", tl.Manufacturer, tl.Id, tl.Version, tl.DeviceType, tl.Media)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	if s := t.Security; s != nil {
+		_, _ = fmt.Fprintf(w, "security\tmode %d (%s), decrypted %t\n", s.Mode, s.Name, s.Decrypted)
+	}
+	if t.Error != "" {
+		_, _ = fmt.Fprintf(w, "error\t%s\n", t.Error)
+	}
+	if t.Payload != "" {
+		_, _ = fmt.Fprintf(w, "payload\t%s\n", t.Payload)
+	}
+	_ = w.Flush()
+	if len(t.Records) == 0 {
+		_, _ = fmt.Fprintln(out)
+		return
+	}
+	_, _ = fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "DIF\tVIF\tQUANTITY\tFUNCTION\tSTORAGE\tTARIFF\tSUBUNIT\tVALUE\tUNIT")
+	for _, r := range t.Records {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%v\t%s\n", r.DIF, r.VIF, r.Quantity, r.Function, r.Storage, r.Tariff, r.Subunit, r.Value, r.Unit)
+	}
+	_ = w.Flush()
+	_, _ = fmt.Fprintln(out)
+}
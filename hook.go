@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// hook passes a reading to a running connector with a shell source, to be called by wmbusmeters with
+// shell=mgw-wmbus-dc hook -socket <path> "$METER_JSON". Returns the exit code.
+func hook(args []string) int {
+	flags := flag.NewFlagSet("hook", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "usage: mgw-wmbus-dc hook [flags] [json]")
+		_, _ = fmt.Fprintln(flags.Output(), "the reading is taken from METER_JSON or stdin if not given")
+		flags.PrintDefaults()
+	}
+	socket := flags.String("socket", "/run/mgw-wmbus-dc.sock", "path of the shell source socket")
+	_ = flags.Parse(args)
+
+	var reading []byte
+	switch {
+	case flags.NArg() > 0:
+		reading = []byte(flags.Arg(0))
+	case os.Getenv("METER_JSON") != "":
+		reading = []byte(os.Getenv("METER_JSON"))
+	default:
+		var err error
+		reading, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	// the connector reads one reading per line
+	line := &bytes.Buffer{}
+	err := json.Compact(line, reading)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "invalid reading:", err)
+		return 1
+	}
+	line.WriteByte('\n')
+
+	conn, err := net.DialTimeout("unix", *socket, 5*time.Second)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer conn.Close()
+	_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write(line.Bytes())
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 	"syscall"
 	"time"
@@ -28,8 +29,11 @@ import (
 	sb_util "github.com/SENERGY-Platform/go-service-base/util"
 	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/configuration"
 	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/discovery"
 	nimbusmgw "github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/nimbus_mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/wmbus"
 )
@@ -42,6 +46,20 @@ func main() {
 		os.Exit(ec)
 	}()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			ec = replay(os.Args[2:])
+			return
+		case "decode":
+			ec = decode(os.Args[2:])
+			return
+		case "hook":
+			ec = hook(os.Args[2:])
+			return
+		}
+	}
+
 	srvInfoHdl := srv_info_hdl.New("github.com/SENERGY-Platform/mgw-wmbus-dc", version)
 
 	config.ParseFlags()
@@ -67,12 +85,25 @@ func main() {
 
 	wg := &sync.WaitGroup{}
 
+	// the mgw client outlives the pipeline, so that pending events can still be sent on shutdown
+	clientCtx, clientCf := context.WithCancel(context.Background())
+	defer clientCf()
+	clientWg := &sync.WaitGroup{}
+
+	checkpoints, err := checkpoint.NewStore(cfg.CheckpointFile, time.Duration(cfg.CheckpointFlushInterval), ctx, wg)
+	if err != nil {
+		util.Logger.Error("unable to open checkpoint store", "file", cfg.CheckpointFile, "err", err)
+		cf()
+		ec = 1
+		return
+	}
+
 	var dm *nimbusmgw.DeviceManager
 
 	mgwClient, err := mgw.New[nimbusmgw.Device](configuration.Config{
 		ConnectorId:   "mgw-wmbus-dc",
 		MgwMqttBroker: cfg.MqttConnStr,
-	}, ctx, wg, func() {
+	}, clientCtx, clientWg, func() {
 		for dm == nil {
 			time.Sleep(time.Second)
 		}
@@ -81,10 +112,51 @@ func main() {
 	if err != nil {
 		util.Logger.Error("unable to create mgw client", "err", err)
 		cf()
+		ec = 1
+		return
+	}
+	dm = nimbusmgw.NewDeviceManager(mgwClient, checkpoints)
+
+	sinks, err := sink.New(cfg.Sinks, mgwClient, os.Stdout)
+	if err != nil {
+		util.Logger.Error("unable to create sinks", "err", err)
+		cf()
+		ec = 1
+		return
+	}
+	s := sink.NewDynamic(sinks)
+
+	reloader := config.NewReloader(config.ConfPath, cfg)
+	reloader.OnChange(func(old *config.Config, new *config.Config) {
+		if reflect.DeepEqual(old.Sinks, new.Sinks) {
+			return
+		}
+		sinks, err := sink.New(new.Sinks, mgwClient, os.Stdout)
+		if err != nil {
+			util.Logger.Error("unable to create sinks, keeping current sinks", "err", err)
+			return
+		}
+		s.Set(sinks)
+	})
+	err = reloader.Start(ctx, wg)
+	if err != nil {
+		util.Logger.Error("unable to watch config", "err", err)
+		cf()
+		ec = 1
 		return
 	}
-	dm = nimbusmgw.NewDeviceManager(mgwClient)
-	wmbus.NewLogForwarder(cfg, mgwClient, dm, ctx, cf, wg)
+
+	dm.StartSync(clientCtx, clientWg, cfg.DeviceSync)
+	dm.StartLivenessCheck(ctx, wg, reloader)
+
+	disc := discovery.New(checkpoints)
+	for _, serviceId := range []string{discovery.ListServiceId, discovery.ApproveServiceId, discovery.RejectServiceId} {
+		mgwClient.RegisterService(serviceId, func(_ nimbusmgw.Device, input interface{}) (interface{}, error) {
+			return disc.HandleCommand(serviceId, input)
+		}, nil)
+	}
+
+	forwarder := wmbus.NewLogForwarder(reloader, s, dm, checkpoints, disc, ctx, cf, wg)
 
 	wg.Add(1)
 	go func() {
@@ -94,4 +166,16 @@ func main() {
 	}()
 
 	wg.Wait()
+
+	// shutdown order: the sources have stopped, pending aggregates are sent, then everything they stored is
+	// flushed and only then the mgw client disconnects
+	if forwarder != nil {
+		forwarder.Close()
+	}
+	err = checkpoints.Flush()
+	if err != nil {
+		util.Logger.Error("unable to flush checkpoints", "file", cfg.CheckpointFile, "err", err)
+	}
+	clientCf()
+	clientWg.Wait()
 }
@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aggregate
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/measurement"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/units"
+)
+
+// Batch is the aggregate of the readings or the telegrams of a meter during one interval.
+type Batch struct {
+	MeterId string
+	Payload []byte // decrypted event of the last reading
+	Reading *model.Reading
+	// Telegram is the last telegram of the interval, received by Radio. Batches of telegrams have no
+	// Payload and Reading.
+	Telegram *model.EncryptedMessage
+	Radio    string
+}
+
+// Aggregator collects the readings and telegrams of each meter for the interval of its policy and hands
+// them to flush as one batch when the interval is over. Pending batches are only handed over by Flush.
+type Aggregator struct {
+	windows map[string]*window
+	mux     sync.Mutex
+	flush   func(b Batch)
+}
+
+type window struct {
+	meterId  string
+	policy   config.Aggregation
+	start    time.Time
+	end      time.Time
+	due      time.Time
+	samples  int
+	payload  []byte
+	telegram *model.EncryptedMessage
+	radio    string
+	last     *model.Reading
+	fields   []string // in order of first appearance
+	series   map[string]*series
+}
+
+type series struct {
+	last  model.Measurement
+	min   float64
+	max   float64
+	sum   float64
+	n     int
+	flags []string
+}
+
+func New(ctx context.Context, wg *sync.WaitGroup, flush func(b Batch)) *Aggregator {
+	a := &Aggregator{
+		windows: map[string]*window{},
+		flush:   flush,
+	}
+	ticker := time.NewTicker(time.Second)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				a.flushDue(now)
+			}
+		}
+	}()
+	return a
+}
+
+// Add adds a reading of a meter to its current interval, which starts with the reading if there is none.
+func (a *Aggregator) Add(meterId string, policy config.Aggregation, payload []byte, r *model.Reading) {
+	t := time.Now()
+	if r.Timestamp != nil {
+		t = *r.Timestamp
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	w := a.window(meterId, policy, t)
+	w.meterId = meterId
+	w.end = t
+	w.samples++
+	w.payload = payload
+	w.last = r
+	for _, m := range r.Measurements {
+		s, ok := w.series[m.Field]
+		if !ok {
+			s = &series{min: m.Value, max: m.Value}
+			w.series[m.Field] = s
+			w.fields = append(w.fields, m.Field)
+		}
+		s.last = m
+		s.min = min(s.min, m.Value)
+		s.max = max(s.max, m.Value)
+		s.sum += m.Value
+		s.n++
+		for _, f := range m.Flags {
+			if !slices.Contains(s.flags, f) {
+				s.flags = append(s.flags, f)
+			}
+		}
+	}
+}
+
+// AddTelegram keeps msg if it is the last telegram of its meter received by radio in the current interval.
+func (a *Aggregator) AddTelegram(radio string, policy config.Aggregation, msg *model.EncryptedMessage) {
+	t := time.Now()
+	if msg.Timestamp != nil {
+		t = *msg.Timestamp
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	w := a.window("telegram/"+radio+"/"+msg.MeterId, policy, t)
+	w.meterId = msg.MeterId
+	w.end = t
+	w.samples++
+	w.telegram = msg
+	w.radio = radio
+}
+
+// window returns the current window under key, starting a new one at t if there is none. a.mux has to be held.
+func (a *Aggregator) window(key string, policy config.Aggregation, t time.Time) *window {
+	w, ok := a.windows[key]
+	if !ok {
+		w = &window{
+			policy: policy,
+			start:  t,
+			due:    time.Now().Add(time.Duration(policy.Interval)),
+			series: map[string]*series{},
+		}
+		a.windows[key] = w
+	}
+	return w
+}
+
+// Flush hands over all pending batches. On shutdown, it has to be called once no more readings are added
+// and before the sinks are closed.
+func (a *Aggregator) Flush() {
+	a.flushDue(time.Time{})
+}
+
+// flushDue flushes the batches due at now, all if now is zero.
+func (a *Aggregator) flushDue(now time.Time) {
+	batches := []Batch{}
+	a.mux.Lock()
+	for key, w := range a.windows {
+		if !now.IsZero() && now.Before(w.due) {
+			continue
+		}
+		delete(a.windows, key)
+		if w.telegram != nil {
+			batches = append(batches, Batch{MeterId: w.meterId, Telegram: w.telegram, Radio: w.radio})
+			continue
+		}
+		batches = append(batches, Batch{MeterId: w.meterId, Payload: w.payload, Reading: w.reading()})
+	}
+	a.mux.Unlock()
+	for _, b := range batches {
+		a.flush(b)
+	}
+}
+
+// reading combines the readings of w into a copy of the last one.
+func (w *window) reading() *model.Reading {
+	r := *w.last
+	function := w.policy.Function
+	if function == "" {
+		function = config.AggregateLast
+	}
+	r.Aggregate = &model.Aggregate{
+		Function: function,
+		Samples:  w.samples,
+		Start:    w.start,
+		End:      w.end,
+	}
+	r.Measurements = make([]model.Measurement, 0, len(w.fields))
+	for _, field := range w.fields {
+		s := w.series[field]
+		m := s.last
+		m.Flags = s.flags
+		if !measurement.Cumulative(m.Quantity) {
+			switch function {
+			case config.AggregateMin:
+				m.Value = s.min
+			case config.AggregateMax:
+				m.Value = s.max
+			case config.AggregateMean:
+				m.Value = units.Round(s.sum / float64(s.n))
+			}
+		}
+		r.Measurements = append(r.Measurements, m)
+	}
+	return &r
+}
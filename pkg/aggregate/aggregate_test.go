@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aggregate
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	sb_config_types "github.com/SENERGY-Platform/go-service-base/config-hdl/types"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+)
+
+func TestAggregator(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	reading := func(minutes int, total float64, temperature float64, flags ...string) *model.Reading {
+		ts := start.Add(time.Duration(minutes) * time.Minute)
+		return &model.Reading{MeterId: "12345678", Timestamp: &ts, Measurements: []model.Measurement{
+			{Field: "total_m3", Quantity: "volume", Value: total, Unit: "m3", Flags: flags},
+			{Field: "flow_temperature_c", Quantity: "temperature", Value: temperature, Unit: "°C"},
+		}}
+	}
+	tests := []struct {
+		function    string
+		temperature float64
+	}{
+		{"", 30},
+		{config.AggregateLast, 30},
+		{config.AggregateMin, 10},
+		{config.AggregateMax, 30},
+		{config.AggregateMean, 20},
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.function, "default"), func(t *testing.T) {
+			ctx, cf := context.WithCancel(context.Background())
+			wg := &sync.WaitGroup{}
+			defer wg.Wait()
+			defer cf()
+			var batches []Batch
+			a := New(ctx, wg, func(b Batch) {
+				batches = append(batches, b)
+			})
+			policy := config.Aggregation{Interval: sb_config_types.Duration(time.Hour), Function: tt.function}
+			a.Add("12345678", policy, []byte("1"), reading(0, 1, 20))
+			a.Add("12345678", policy, []byte("2"), reading(10, 2, 10, config.FlagSpike))
+			a.Add("12345678", policy, []byte("3"), reading(20, 3, 30))
+			a.Flush()
+			if len(batches) != 1 {
+				t.Fatalf("%d batches, want 1", len(batches))
+			}
+			b := batches[0]
+			if b.MeterId != "12345678" || string(b.Payload) != "3" || b.Telegram != nil {
+				t.Errorf("batch %+v", b)
+			}
+			agg := b.Reading.Aggregate
+			if agg == nil || agg.Samples != 3 || !agg.Start.Equal(start) || !agg.End.Equal(start.Add(20*time.Minute)) {
+				t.Errorf("aggregate %+v", agg)
+			}
+			m := b.Reading.Measurements
+			if len(m) != 2 || m[0].Value != 3 || !slices.Equal(m[0].Flags, []string{config.FlagSpike}) || m[1].Value != tt.temperature {
+				t.Errorf("measurements %+v, want total 3 with spike flag, temperature %v", m, tt.temperature)
+			}
+			a.Flush()
+			if len(batches) != 1 {
+				t.Errorf("%d batches after second flush, want 1", len(batches))
+			}
+		})
+	}
+}
+
+func TestAggregatorTelegrams(t *testing.T) {
+	ctx, cf := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cf()
+	batches := map[string]Batch{}
+	a := New(ctx, wg, func(b Batch) {
+		batches[b.Radio+"/"+b.MeterId] = b
+	})
+	policy := config.Aggregation{Interval: sb_config_types.Duration(time.Hour)}
+	now := time.Now()
+	for i, radio := range []string{"r1", "r1", "r2"} {
+		a.AddTelegram(radio, policy, &model.EncryptedMessage{MeterId: "12345678", Telegram: string(rune('a' + i)), Timestamp: &now})
+	}
+	a.Add("12345678", policy, nil, &model.Reading{MeterId: "12345678", Timestamp: &now})
+	a.Flush()
+	if len(batches) != 3 {
+		t.Fatalf("%d batches, want 3", len(batches))
+	}
+	if b := batches["r1/12345678"]; b.Telegram == nil || b.Telegram.Telegram != "b" || b.Reading != nil {
+		t.Errorf("batch of r1 %+v, want last telegram", b)
+	}
+	if b := batches["r2/12345678"]; b.Telegram == nil || b.Telegram.Telegram != "c" {
+		t.Errorf("batch of r2 %+v", b)
+	}
+	if b := batches["/12345678"]; b.Reading == nil || b.Telegram != nil {
+		t.Errorf("batch of reading %+v", b)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alarm
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const (
+	LowBattery       = "low_battery"
+	Leak             = "leak"
+	Burst            = "burst"
+	Tamper           = "tamper"
+	Backflow         = "backflow"
+	Dry              = "dry"
+	ApplicationError = "application_error"
+	Abnormal         = "abnormal_condition"
+	PermanentError   = "permanent_error"
+	TemporaryError   = "temporary_error"
+	Error            = "error" // error the meter reports without known cause
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+)
+
+var severities = map[string]string{
+	Leak:             SeverityCritical,
+	Burst:            SeverityCritical,
+	Tamper:           SeverityError,
+	Backflow:         SeverityError,
+	Abnormal:         SeverityError,
+	PermanentError:   SeverityError,
+	LowBattery:       SeverityWarning,
+	Dry:              SeverityWarning,
+	ApplicationError: SeverityWarning,
+	TemporaryError:   SeverityWarning,
+	Error:            SeverityWarning,
+}
+
+const (
+	SourceTelegram = "telegram" // status byte of the transport layer
+	SourceReading  = "reading"  // status fields of wmbusmeters readings
+)
+
+// statusFields are the fields in which wmbusmeters drivers report the meter's status
+var statusFields = []string{"current_status", "status"}
+
+// statusTokens map parts of wmbusmeters status values to alarms, the first match wins.
+type statusToken struct {
+	token string
+	alarm string
+}
+
+var statusTokens = []statusToken{
+	{"LEAK", Leak},
+	{"BURST", Burst},
+	{"DRY", Dry},
+	{"REVERSE", Backflow},
+	{"BACKFLOW", Backflow},
+	{"BACK_FLOW", Backflow},
+	{"TAMPER", Tamper},
+	{"MAGNET", Tamper},
+	{"FRAUD", Tamper},
+	{"BATTERY", LowBattery},
+	{"POWER_LOW", LowBattery},
+	{"PERMANENT", PermanentError},
+	{"TEMPORARY", TemporaryError},
+	{"ERROR", Error},
+	{"FAULT", Error},
+	{"FAIL", Error},
+	{"ALARM", Error},
+	{"DEFECT", Error},
+	{"MALFUNCTION", Error},
+}
+
+// FromStatusByte returns the alarms of the status byte of a telegram's transport layer, as defined by EN 13757-3.
+func FromStatusByte(status byte) map[string]string {
+	alarms := map[string]string{}
+	switch status & 0x03 {
+	case 0x02:
+		alarms[ApplicationError] = "application error"
+	case 0x03:
+		alarms[Abnormal] = "abnormal condition or alarm"
+	}
+	if status&0x04 != 0 {
+		alarms[LowBattery] = "power low"
+	}
+	if status&0x08 != 0 {
+		alarms[PermanentError] = "permanent error"
+	}
+	if status&0x10 != 0 {
+		alarms[TemporaryError] = "temporary error"
+	}
+	return alarms
+}
+
+// FromReading returns the alarms in the status fields of a wmbusmeters reading, with the status tokens that
+// raised them as message. Unknown tokens raise no alarm. It returns false if the reading has no status field.
+func FromReading(j map[string]any) (map[string]string, bool) {
+	alarms := map[string]string{}
+	found := false
+	for _, field := range statusFields {
+		status, ok := j[field].(string)
+		if !ok {
+			continue
+		}
+		found = true
+		for _, token := range strings.Fields(strings.ToUpper(status)) {
+			if token == "OK" || token == "NULL" {
+				continue
+			}
+			i := slices.IndexFunc(statusTokens, func(t statusToken) bool { return strings.Contains(token, t.token) })
+			if i < 0 {
+				util.Logger.Debug("unknown status token", "field", field, "token", token)
+				continue
+			}
+			alarm := statusTokens[i].alarm
+			msg, ok := alarms[alarm]
+			switch {
+			case !ok:
+				alarms[alarm] = token
+			case !slices.Contains(strings.Fields(msg), token):
+				alarms[alarm] = msg + " " + token
+			}
+		}
+	}
+	return alarms, found
+}
+
+// Tracker detects changes of the active alarms of meters. An alarm is active while any source reports it, so
+// it is raised by the first and cleared by the last source.
+type Tracker struct {
+	meters *checkpoint.Bucket[meterAlarms]
+	mux    sync.Mutex
+}
+
+// meterAlarms are the alarms of a meter per source, with the time each alarm was raised.
+type meterAlarms struct {
+	Active  map[string]map[string]time.Time `json:"active,omitempty"`
+	Updated map[string]time.Time            `json:"updated"` // time of the last update per source
+}
+
+const bucket = "alarms"
+
+// New creates a Tracker. Active alarms survive restarts if store is not nil.
+func New(store *checkpoint.Store) *Tracker {
+	return &Tracker{meters: checkpoint.NewBucket[meterAlarms](store, bucket)}
+}
+
+// Update sets the alarms of a meter reported by source at t, given as alarm to message, and returns alerts
+// for alarms that were raised or cleared since the last update. Updates older than the last one of source,
+// e.g. of late telegrams, are ignored.
+func (tr *Tracker) Update(meterId string, source string, alarms map[string]string, t time.Time) []model.Alert {
+	tr.mux.Lock()
+	defer tr.mux.Unlock()
+	m, _ := tr.meters.Get(meterId)
+	if last, ok := m.Updated[source]; ok && t.Before(last) {
+		return nil
+	}
+	prev := m.Active[source]
+	next := map[string]time.Time{}
+	alerts := []model.Alert{}
+	for _, typ := range slices.Sorted(maps.Keys(alarms)) {
+		since, ok := prev[typ]
+		if !ok {
+			since = t
+			if !m.activeElsewhere(source, typ) {
+				alerts = append(alerts, alert(meterId, source, typ, model.AlertRaised, since, t, alarms[typ]))
+			}
+		}
+		next[typ] = since
+	}
+	for _, typ := range slices.Sorted(maps.Keys(prev)) {
+		if _, ok := alarms[typ]; !ok && !m.activeElsewhere(source, typ) {
+			alerts = append(alerts, alert(meterId, source, typ, model.AlertCleared, prev[typ], t, ""))
+		}
+	}
+	if m.Active == nil {
+		m.Active = map[string]map[string]time.Time{}
+	}
+	if m.Updated == nil {
+		m.Updated = map[string]time.Time{}
+	}
+	if len(next) == 0 {
+		delete(m.Active, source)
+	} else {
+		m.Active[source] = next
+	}
+	m.Updated[source] = t
+	tr.meters.Set(meterId, m)
+	return alerts
+}
+
+// activeElsewhere reports whether a source other than source has the alarm active.
+func (m meterAlarms) activeElsewhere(source string, typ string) bool {
+	for s, active := range m.Active {
+		if _, ok := active[typ]; ok && s != source {
+			return true
+		}
+	}
+	return false
+}
+
+func alert(meterId string, source string, typ string, state string, since time.Time, t time.Time, message string) model.Alert {
+	return model.Alert{
+		MeterId:   meterId,
+		Type:      typ,
+		Severity:  severities[typ],
+		State:     state,
+		Since:     since,
+		Source:    source,
+		Message:   message,
+		Timestamp: t,
+	}
+}
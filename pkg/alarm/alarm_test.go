@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alarm
+
+import (
+	"io"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+func TestFromStatusByte(t *testing.T) {
+	tests := []struct {
+		status byte
+		want   []string
+	}{
+		{0x00, nil},
+		{0x02, []string{ApplicationError}},
+		{0x03, []string{Abnormal}},
+		{0x04, []string{LowBattery}},
+		{0x1C, []string{LowBattery, PermanentError, TemporaryError}},
+	}
+	for _, tt := range tests {
+		got := FromStatusByte(tt.status)
+		if len(got) != len(tt.want) {
+			t.Errorf("FromStatusByte(0x%02X) = %v, want %v", tt.status, got, tt.want)
+			continue
+		}
+		for _, typ := range tt.want {
+			if _, ok := got[typ]; !ok {
+				t.Errorf("FromStatusByte(0x%02X) = %v, want %v", tt.status, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestFromReading(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	tests := []struct {
+		name  string
+		j     map[string]any
+		want  map[string]string
+		found bool
+	}{
+		{"no status", map[string]any{"total_m3": 1.0}, map[string]string{}, false},
+		{"ok", map[string]any{"current_status": "OK"}, map[string]string{}, true},
+		{"known", map[string]any{"current_status": "LEAK DRY"}, map[string]string{Leak: "LEAK", Dry: "DRY"}, true},
+		{"battery", map[string]any{"status": "battery_low"}, map[string]string{LowBattery: "BATTERY_LOW"}, true},
+		{"unknown", map[string]any{"status": "SOMETHING"}, map[string]string{}, true},
+		{"unknown and known", map[string]any{"status": "SOMETHING LEAK"}, map[string]string{Leak: "LEAK"}, true},
+		{"error", map[string]any{"status": "ERROR_FLAGS SOMETHING"}, map[string]string{Error: "ERROR_FLAGS"}, true},
+		{"errors", map[string]any{"status": "HW_FAULT SENSOR_FAIL"}, map[string]string{Error: "HW_FAULT SENSOR_FAIL"}, true},
+		{"both fields", map[string]any{"current_status": "TAMPER", "status": "TAMPER MAGNET"}, map[string]string{Tamper: "TAMPER MAGNET"}, true},
+	}
+	for _, tt := range tests {
+		got, found := FromReading(tt.j)
+		if found != tt.found || !maps.Equal(got, tt.want) {
+			t.Errorf("%s: FromReading = %v, %v, want %v, %v", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	battery := map[string]string{LowBattery: "power low"}
+	none := map[string]string{}
+	type alert struct{ typ, state, source string }
+	steps := []struct {
+		name    string
+		source  string
+		alarms  map[string]string
+		minutes int
+		want    []alert
+	}{
+		{"raised by telegram", SourceTelegram, battery, 0, []alert{{LowBattery, model.AlertRaised, SourceTelegram}}},
+		{"still active", SourceTelegram, battery, 1, nil},
+		{"also reported by reading", SourceReading, battery, 1, nil},
+		{"late telegram", SourceTelegram, none, 0, nil},
+		{"cleared by telegram, reading still active", SourceTelegram, none, 2, nil},
+		{"cleared by reading", SourceReading, none, 2, []alert{{LowBattery, model.AlertCleared, SourceReading}}},
+		{"raised again", SourceReading, map[string]string{Leak: "LEAK"}, 3, []alert{{Leak, model.AlertRaised, SourceReading}}},
+	}
+	tr := New(nil)
+	for _, step := range steps {
+		alerts := tr.Update("12345678", step.source, step.alarms, start.Add(time.Duration(step.minutes)*time.Minute))
+		if len(alerts) != len(step.want) {
+			t.Fatalf("%s: got %+v, want %+v", step.name, alerts, step.want)
+		}
+		for i, a := range alerts {
+			if got := (alert{a.Type, a.State, a.Source}); got != step.want[i] {
+				t.Errorf("%s: got %+v, want %+v", step.name, got, step.want[i])
+			}
+		}
+	}
+}
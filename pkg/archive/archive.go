@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const suffix = ".jsonl"
+
+// Archive keeps the full-resolution readings of all meters in one JSON lines file per meter and day,
+// named <dir>/<meter id>/<YYYY-MM-DD>.jsonl. Archived files can be replayed like meter reading files.
+// Files older than days are removed daily.
+type Archive struct {
+	dir string
+	mux sync.Mutex
+}
+
+func New(dir string, days int, ctx context.Context, wg *sync.WaitGroup) (*Archive, error) {
+	err := os.MkdirAll(dir, 0744)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{dir: dir}
+	if days == 0 {
+		return a, nil
+	}
+	ticker := time.NewTicker(24 * time.Hour)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		a.removeBefore(time.Now().UTC().AddDate(0, 0, -days))
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				a.removeBefore(now.UTC().AddDate(0, 0, -days))
+			}
+		}
+	}()
+	return a, nil
+}
+
+// Write appends the reading payload of a meter, received at t, to the file of its day.
+func (a *Archive) Write(meterId string, t time.Time, payload []byte) (err error) {
+	if !config.ValidMeterId(meterId) {
+		return fmt.Errorf("invalid meter id %q", meterId)
+	}
+	dir := filepath.Join(a.dir, meterId)
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	err = os.MkdirAll(dir, 0744)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, t.UTC().Format(time.DateOnly)+suffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := f.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	_, err = f.Write(append(payload, '\n'))
+	return err
+}
+
+// removeBefore removes the files of days before t and meter directories left empty.
+func (a *Archive) removeBefore(t time.Time) {
+	limit := t.Format(time.DateOnly)
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	meters, err := os.ReadDir(a.dir)
+	if err != nil {
+		util.Logger.Error("unable to read archive", "dir", a.dir, "err", err)
+		return
+	}
+	for _, meter := range meters {
+		if !meter.IsDir() {
+			continue
+		}
+		dir := filepath.Join(a.dir, meter.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			util.Logger.Warn("unable to read archive", "dir", dir, "err", err)
+			continue
+		}
+		removed := 0
+		for _, f := range files {
+			day, ok := strings.CutSuffix(f.Name(), suffix)
+			if !ok || day >= limit {
+				continue
+			}
+			err = os.Remove(filepath.Join(dir, f.Name()))
+			if err != nil {
+				util.Logger.Warn("unable to remove archived readings", "file", f.Name(), "err", err)
+				continue
+			}
+			removed++
+		}
+		if removed == len(files) {
+			err = os.Remove(dir)
+			if err != nil {
+				util.Logger.Warn("unable to remove archive dir", "dir", dir, "err", err)
+			}
+		}
+	}
+}
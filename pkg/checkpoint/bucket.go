@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// Bucket is a typed bucket of a store whose values are cached in memory after first use. If the store
+// is nil, values are only kept in memory, e.g. for replays.
+type Bucket[T any] struct {
+	store *Store
+	name  string
+	cache map[string]T
+	mux   sync.Mutex
+}
+
+func NewBucket[T any](store *Store, name string) *Bucket[T] {
+	return &Bucket[T]{
+		store: store,
+		name:  name,
+		cache: map[string]T{},
+	}
+}
+
+// Get returns the value stored under key and whether there is one. Unreadable values are logged and
+// reported as missing.
+func (b *Bucket[T]) Get(key string) (T, bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if v, ok := b.cache[key]; ok {
+		return v, true
+	}
+	var v T
+	if b.store == nil {
+		return v, false
+	}
+	ok, err := b.store.Get(b.name, key, &v)
+	if err != nil {
+		util.Logger.Warn("unable to read checkpoint", "bucket", b.name, "key", key, "err", err)
+		var zero T
+		return zero, false
+	}
+	if ok {
+		b.cache[key] = v
+	}
+	return v, ok
+}
+
+// Set stores v under key. Errors are logged, the value is kept in memory anyway.
+func (b *Bucket[T]) Set(key string, v T) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.cache[key] = v
+	if b.store == nil {
+		return
+	}
+	err := b.store.Set(b.name, key, v)
+	if err != nil {
+		util.Logger.Warn("unable to store checkpoint", "bucket", b.name, "key", key, "err", err)
+	}
+}
+
+func (b *Bucket[T]) Delete(key string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	delete(b.cache, key)
+	if b.store != nil {
+		b.store.Delete(b.name, key)
+	}
+}
+
+// Keys returns the sorted keys of the bucket. Without store, only values set since start are known.
+func (b *Bucket[T]) Keys() []string {
+	if b.store != nil {
+		return b.store.Keys(b.name)
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	keys := make([]string, 0, len(b.cache))
+	for k := range b.cache {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+func TestBucket(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "checkpoints.json")
+	s, _, _ := openStore(t, file, time.Hour)
+	b := NewBucket[map[string]float64](s, "counters")
+	if _, ok := b.Get("a"); ok {
+		t.Fatal("empty bucket has a value")
+	}
+	b.Set("a", map[string]float64{"total": 1.5})
+	b.Set("b", map[string]float64{"total": 2})
+	b.Set("c", map[string]float64{"total": 3})
+	b.Delete("c")
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, _, _ := openStore(t, file, time.Hour)
+	rb := NewBucket[map[string]float64](reloaded, "counters")
+	if keys := rb.Keys(); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("keys %v, want [a b]", keys)
+	}
+	if v, ok := rb.Get("a"); !ok || v["total"] != 1.5 {
+		t.Errorf("Get(a) = %v, %v, want total 1.5", v, ok)
+	}
+	// values of another type are reported as missing
+	if v, ok := NewBucket[string](reloaded, "counters").Get("a"); ok {
+		t.Errorf("Get(a) as string = %q, want missing", v)
+	}
+}
+
+func TestBucketWithoutStore(t *testing.T) {
+	b := NewBucket[int](nil, "counters")
+	b.Set("b", 2)
+	b.Set("a", 1)
+	if v, ok := b.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1", v, ok)
+	}
+	b.Delete("a")
+	if keys := b.Keys(); !slices.Equal(keys, []string{"b"}) {
+		t.Errorf("keys %v, want [b]", keys)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// Store is a single file key-value store for persistent state like tail positions.
+// Values are kept in memory and written to disk in batches, at most once per flush interval
+// and once more by the owner on shutdown, to limit write amplification on flash storage.
+type Store struct {
+	file    string
+	buckets map[string]map[string]json.RawMessage
+	dirty   bool
+	mux     sync.Mutex
+}
+
+// NewStore opens the store in file and flushes it periodically until ctx is done. The final Flush is left to
+// the caller, to be done once all writers have stopped, so that no late Set is lost.
+func NewStore(file string, flushInterval time.Duration, ctx context.Context, wg *sync.WaitGroup) (*Store, error) {
+	err := os.MkdirAll(filepath.Dir(file), 0744)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := s.Flush()
+				if err != nil {
+					util.Logger.Error("unable to flush checkpoints", "file", file, "err", err)
+				}
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Load reads the store in file without flushing it, e.g. to inspect the checkpoints of a running service.
+// A missing file results in an empty store.
+func Load(file string) (*Store, error) {
+	s := &Store{
+		file:    file,
+		buckets: map[string]map[string]json.RawMessage{},
+		mux:     sync.Mutex{},
+	}
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &s.buckets)
+		if err != nil {
+			util.Logger.Error("unable to unmarshal checkpoint file, starting empty", "file", file, "err", err)
+			s.buckets = map[string]map[string]json.RawMessage{}
+		}
+	}
+	return s, nil
+}
+
+// Get unmarshals the value stored under bucket and key into v and reports whether it was found.
+func (s *Store) Get(bucket string, key string, v any) (bool, error) {
+	s.mux.Lock()
+	data, ok := s.buckets[bucket][key]
+	s.mux.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// Set stores v under bucket and key. The value is persisted with the next flush.
+func (s *Store) Set(bucket string, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	b, ok := s.buckets[bucket]
+	if !ok {
+		b = map[string]json.RawMessage{}
+		s.buckets[bucket] = b
+	}
+	b[key] = data
+	s.dirty = true
+	return nil
+}
+
+func (s *Store) Delete(bucket string, key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.buckets[bucket][key]; !ok {
+		return
+	}
+	delete(s.buckets[bucket], key)
+	s.dirty = true
+}
+
+// Keys returns the sorted keys of a bucket.
+func (s *Store) Keys(bucket string) []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for k := range s.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Flush writes all buckets to disk if anything changed since the last flush.
+// The file is replaced atomically, so a crash never leaves a partially written store.
+func (s *Store) Flush() error {
+	s.mux.Lock()
+	if !s.dirty {
+		s.mux.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(s.buckets)
+	s.dirty = false
+	s.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := s.file + ".tmp"
+	err = writeFileSync(tmp, data)
+	if err != nil {
+		s.markDirty()
+		return err
+	}
+	err = os.Rename(tmp, s.file)
+	if err != nil {
+		s.markDirty()
+		return err
+	}
+	return nil
+}
+
+func (s *Store) markDirty() {
+	s.mux.Lock()
+	s.dirty = true
+	s.mux.Unlock()
+}
+
+func writeFileSync(file string, data []byte) (err error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		cerr := f.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return
+	}
+	err = f.Sync()
+	return
+}
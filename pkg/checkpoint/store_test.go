@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+type position struct {
+	Offset int64 `json:"offset"`
+}
+
+func openStore(t *testing.T, file string, flushInterval time.Duration) (*Store, context.CancelFunc, *sync.WaitGroup) {
+	t.Helper()
+	ctx, cf := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	s, err := NewStore(file, flushInterval, ctx, wg)
+	if err != nil {
+		cf()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cf()
+		wg.Wait()
+	})
+	return s, cf, wg
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "sub", "checkpoints.json")
+	s, _, _ := openStore(t, file, time.Hour)
+	for key, offset := range map[string]int64{"b": 2, "a": 1, "c": 3} {
+		if err := s.Set("tail", key, position{Offset: offset}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Delete("tail", "c")
+	s.Delete("tail", "missing")
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left after flush: %v", err)
+	}
+
+	reloaded, _, _ := openStore(t, file, time.Hour)
+	if keys := reloaded.Keys("tail"); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("keys %v, want [a b]", keys)
+	}
+	var p position
+	ok, err := reloaded.Get("tail", "b", &p)
+	if err != nil || !ok || p.Offset != 2 {
+		t.Errorf("Get = %v, %v, %+v, want offset 2", ok, err, p)
+	}
+	ok, err = reloaded.Get("other", "b", &p)
+	if err != nil || ok {
+		t.Errorf("Get of other bucket = %v, %v, want not found", ok, err)
+	}
+}
+
+func TestStoreFlushOnlyIfDirty(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "checkpoints.json")
+	s, _, _ := openStore(t, file, time.Hour)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("clean store was written: %v", err)
+	}
+	_ = s.Set("tail", "a", position{Offset: 1})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// a clean store does not replace the file, e.g. with one written by another process
+	if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "{}" {
+		t.Errorf("clean store flushed again: %s", data)
+	}
+}
+
+func TestStorePeriodicFlush(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "checkpoints.json")
+	s, cf, wg := openStore(t, file, 10*time.Millisecond)
+	_ = s.Set("tail", "a", position{Offset: 1})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(file); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("store was not flushed periodically")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// after shutdown, flushing is left to the owner
+	cf()
+	wg.Wait()
+	_ = s.Set("tail", "b", position{Offset: 2})
+	time.Sleep(30 * time.Millisecond)
+	reloaded, _, _ := openStore(t, file, time.Hour)
+	if keys := reloaded.Keys("tail"); !slices.Equal(keys, []string{"a"}) {
+		t.Errorf("keys after shutdown %v, want [a]", keys)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _, _ = openStore(t, file, time.Hour)
+	if keys := reloaded.Keys("tail"); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("keys after final flush %v, want [a b]", keys)
+	}
+}
+
+func TestStoreCorruptFile(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "checkpoints.json")
+	if err := os.WriteFile(file, []byte(`{"tail":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, _, _ := openStore(t, file, time.Hour)
+	if keys := s.Keys("tail"); len(keys) != 0 {
+		t.Errorf("keys %v, want none", keys)
+	}
+}
@@ -17,33 +17,334 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
 	sb_config_hdl "github.com/SENERGY-Platform/go-service-base/config-hdl"
+	sb_config_types "github.com/SENERGY-Platform/go-service-base/config-hdl/types"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+)
+
+const (
+	SourceTypeLog           = "log"
+	SourceTypeMeterReadings = "meter_readings"
+	SourceTypeJsonStream    = "json_stream" // --format=json output, path is a fifo or - for stdin
+	SourceTypeShell         = "shell"       // readings passed by the hook subcommand, path is a unix socket
+	SourceTypeMqtt          = "mqtt"        // readings published by wmbusmeters, path is a topic filter
+)
+
+const (
+	SinkTypeMgw    = "mgw"
+	SinkTypeStdout = "stdout"
 )
 
 type Config struct {
-	LogLevel              string `json:"log_level" env_var:"LOG_LEVEL"`
-	WmbusLogFile          string `json:"wmbus_log_file" env_var:"WMBUS_LOG_FILE"`
-	WmbusMeterReadingsDir string `json:"wmbus_meter_readings_dir" env_var:"WMBUS_METER_READINGS_DIR"`
-	SeekDir               string `json:"seek_dir" env_var:"SEEK_DIR"`
-	LogBackupDir          string `json:"log_backup_dir" env_var:"LOG_BACKUP_DIR"`
-	MqttConnStr           string `json:"mqtt_conn_str" env_var:"MQTT_CONN_STR"`
-	NimbusId              string `json:"nimbus_id" env_var:"NIMBUS_ID"`
-	NimbusName            string `json:"nimbus_name" env_var:"NIMBUS_NAME"`
-	NimbusDeviceTypeId    string `json:"nimbus_device_type_id" env_var:"NIMBUS_DEVICE_TYPE_ID"`
+	LogLevel                string                   `json:"log_level" env_var:"LOG_LEVEL"`
+	WmbusLogFile            string                   `json:"wmbus_log_file" env_var:"WMBUS_LOG_FILE"`
+	WmbusMeterReadingsDir   string                   `json:"wmbus_meter_readings_dir" env_var:"WMBUS_METER_READINGS_DIR"`
+	SeekDir                 string                   `json:"seek_dir" env_var:"SEEK_DIR"` // only read to migrate seek files of older versions
+	CheckpointFile          string                   `json:"checkpoint_file" env_var:"CHECKPOINT_FILE"`
+	CheckpointFlushInterval sb_config_types.Duration `json:"checkpoint_flush_interval" env_var:"CHECKPOINT_FLUSH_INTERVAL"`
+	LogBackupDir            string                   `json:"log_backup_dir" env_var:"LOG_BACKUP_DIR"`
+	LogBackups              int                      `json:"log_backups" env_var:"LOG_BACKUPS"`
+	ArchiveDir              string                   `json:"archive_dir" env_var:"ARCHIVE_DIR"`   // full-resolution readings are archived here, disabled if empty
+	ArchiveDays             int                      `json:"archive_days" env_var:"ARCHIVE_DAYS"` // days archived readings are kept, forever if 0
+	MqttConnStr             string                   `json:"mqtt_conn_str" env_var:"MQTT_CONN_STR"`
+	NimbusId                string                   `json:"nimbus_id" env_var:"NIMBUS_ID"`
+	NimbusName              string                   `json:"nimbus_name" env_var:"NIMBUS_NAME"`
+	NimbusDeviceTypeId      string                   `json:"nimbus_device_type_id" env_var:"NIMBUS_DEVICE_TYPE_ID"`
+	DeviceSync              DeviceSync               `json:"device_sync"`
+
+	// Radios, Sinks and Meters can only be set in the config file. Without radios, a single radio is
+	// built from WmbusLogFile, WmbusMeterReadingsDir and the Nimbus* fields.
+	Radios   []Radio  `json:"radios"`
+	Sinks    []Sink   `json:"sinks"`
+	Meters   []Meter  `json:"meters"`
+	Pipeline Pipeline `json:"pipeline"`
+}
+
+// Radio is a wmbusmeters instance, registered as nimbus device. Encrypted telegrams are sent as its events.
+type Radio struct {
+	Id           string   `json:"id"`
+	Name         string   `json:"name"`
+	DeviceTypeId string   `json:"device_type_id"`
+	Sources      []Source `json:"sources"`
+}
+
+type Source struct {
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Broker string `json:"broker,omitempty"` // mqtt broker of mqtt sources, mqtt_conn_str if empty
+	// Fields are the selectfields of wmbusmeters, required to read meter reading files in fields or hr format.
+	Fields    []string `json:"fields,omitempty"`
+	Separator string   `json:"separator,omitempty"` // separator of the fields format, ; if empty
+	// Mode and Group set the permissions of the socket of shell sources and of the fifo created for json_stream
+	// sources, so that wmbusmeters can write to them when it runs as another user. Mode is octal, 0660 if empty.
+	// Group is a group name or id, the service's if empty.
+	Mode  string `json:"mode,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+const defaultFileMode = 0660
+
+// FileMode returns the permissions of the socket of a shell source or the fifo of a json_stream source.
+func (s Source) FileMode() (os.FileMode, error) {
+	if s.Mode == "" {
+		return defaultFileMode, nil
+	}
+	mode, err := strconv.ParseUint(s.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not an octal file mode", s.Mode)
+	}
+	return os.FileMode(mode), nil
+}
+
+type Sink struct {
+	Type     string   `json:"type"`
+	Services []string `json:"services,omitempty"` // forward only these services, all if empty
+}
+
+type Meter struct {
+	Id           string                 `json:"id"`
+	Name         string                 `json:"name,omitempty"`
+	Key          sb_config_types.Secret `json:"key,omitempty"`
+	DeviceTypeId string                 `json:"device_type_id,omitempty"`
+	Location     *model.Location        `json:"location,omitempty"`
+	Tags         map[string]string      `json:"tags,omitempty"`
+	// ExpectedInterval is the transmission interval of the meter, learned from its telegrams if not set.
+	ExpectedInterval sb_config_types.Duration `json:"expected_interval,omitempty"`
+	Aggregation      *Aggregation             `json:"aggregation,omitempty"` // replaces pipeline.aggregation
+}
+
+// DeviceSync limits how fast device changes are published to the mgw.
+type DeviceSync struct {
+	Rate          float64                  `json:"rate" env_var:"DEVICE_SYNC_RATE"` // publishes per second
+	MaxRetryDelay sb_config_types.Duration `json:"max_retry_delay" env_var:"DEVICE_SYNC_MAX_RETRY_DELAY"`
+}
+
+type Pipeline struct {
+	MeterDeviceTypeId string    `json:"meter_device_type_id" env_var:"METER_DEVICE_TYPE_ID"` // used for meters without device_type_id
+	Filter            Filter    `json:"filter"`
+	Discovery         Discovery `json:"discovery"`
+	Liveness          Liveness  `json:"liveness"`
+	Mappings          []Mapping `json:"mappings"`
+	// Units are the target units of measurements per quantity, e.g. {"energy": "MWh"}. Quantities without
+	// target unit keep the unit reported by the meter.
+	Units map[string]string `json:"units,omitempty"`
+	// Schemas extend or replace the bundled schemas of meter readings per driver.
+	Schemas       []Schema     `json:"schemas,omitempty"`
+	RejectInvalid bool         `json:"reject_invalid" env_var:"REJECT_INVALID_READINGS"` // drop readings violating their schema instead of removing the invalid fields
+	Plausibility  Plausibility `json:"plausibility"`
+	Derived       Derived      `json:"derived"`
+	Aggregation   Aggregation  `json:"aggregation"`
+}
+
+// Aggregation sends at most one reading and one telegram per meter and interval. Cumulative counters keep their
+// last value, other measurements are combined by Function. The decrypted and encrypted events carry the last
+// reading and telegram of the interval. Alerts are always sent right away.
+type Aggregation struct {
+	Interval sb_config_types.Duration `json:"interval" env_var:"AGGREGATION_INTERVAL"`           // every reading is sent if 0
+	Function string                   `json:"function,omitempty" env_var:"AGGREGATION_FUNCTION"` // last if empty
+}
+
+const (
+	AggregateLast = "last"
+	AggregateMin  = "min"
+	AggregateMax  = "max"
+	AggregateMean = "mean"
+)
+
+var aggregateFunctions = []string{AggregateLast, AggregateMin, AggregateMax, AggregateMean}
+
+// Derived computes the consumption between readings and daily and monthly totals of cumulative counters.
+// Results are sent to the derived service of the meter.
+type Derived struct {
+	Enabled  bool   `json:"enabled" env_var:"DERIVED_ENABLED"`
+	Timezone string `json:"timezone" env_var:"DERIVED_TIMEZONE"` // IANA zone of the midnight days and months start at, local time if empty
+}
+
+// Plausibility checks cumulative counters like volume and energy totals against their previous value.
+type Plausibility struct {
+	Enabled bool `json:"enabled" env_var:"PLAUSIBILITY_ENABLED"`
+	// MaxRates are the highest plausible increases per hour and quantity, e.g. {"volume": {"value": 5, "unit": "m3"}}.
+	// Quantities without max rate are not checked for spikes.
+	MaxRates map[string]Rate `json:"max_rates,omitempty"`
+	// FrozenAfter flags counters that did not change for this long, 0 disables the check.
+	FrozenAfter sb_config_types.Duration `json:"frozen_after" env_var:"PLAUSIBILITY_FROZEN_AFTER"`
+	// Withhold lists the flags of values that are not forwarded, e.g. ["decrease", "spike"].
+	Withhold []string `json:"withhold,omitempty"`
+	// AcceptAfter is the number of consistent withheld readings in a row after which the counter accepts them as
+	// new baseline, e.g. after a meter exchange. 0 withholds them until the counter is plausible again.
+	AcceptAfter int `json:"accept_after" env_var:"PLAUSIBILITY_ACCEPT_AFTER"`
+}
+
+type Rate struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+const (
+	FlagDecrease = "decrease"
+	FlagSpike    = "spike"
+	FlagReset    = "reset"
+	FlagFrozen   = "frozen"
+)
+
+var plausibilityFlags = []string{FlagDecrease, FlagSpike, FlagReset, FlagFrozen}
+
+// Schema describes the fields of the readings of a wmbusmeters driver. Fields of bundled schemas are replaced by
+// fields with the same name. Unknown fields are reported as schema drift if Strict is set.
+type Schema struct {
+	Driver string                 `json:"driver"`
+	Strict bool                   `json:"strict,omitempty"`
+	Fields map[string]SchemaField `json:"fields"`
+}
+
+const (
+	SchemaTypeNumber  = "number"
+	SchemaTypeString  = "string"
+	SchemaTypeBoolean = "boolean"
+)
+
+type SchemaField struct {
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+}
+
+// Mapping overrides how a wmbusmeters field is turned into a measurement. Quantity and Unit are required
+// unless Ignore is set.
+type Mapping struct {
+	Driver        string `json:"driver,omitempty"` // all drivers if empty
+	Field         string `json:"field"`
+	Ignore        bool   `json:"ignore,omitempty"`
+	Quantity      string `json:"quantity,omitempty"`
+	Unit          string `json:"unit,omitempty"`
+	Tariff        int    `json:"tariff,omitempty"`
+	StorageNumber int    `json:"storage_number,omitempty"`
+}
+
+// Liveness marks meters offline, or removes them, after a silence period. Disabled if OfflineAfter is 0.
+type Liveness struct {
+	OfflineAfter        sb_config_types.Duration `json:"offline_after" env_var:"LIVENESS_OFFLINE_AFTER"`
+	MissedTransmissions int                      `json:"missed_transmissions" env_var:"LIVENESS_MISSED_TRANSMISSIONS"`
+	Remove              bool                     `json:"remove" env_var:"LIVENESS_REMOVE"`
+}
+
+// Discovery holds back meters that are neither defined in Meters nor approved by an operator.
+type Discovery struct {
+	Enabled bool `json:"enabled" env_var:"DISCOVERY_ENABLED"`
+}
+
+// Filter decides which meters are forwarded. Deny rules win over allow rules. If allow rules exist,
+// a meter has to match at least one of them. Rules only match known values: a meter whose field of a deny rule
+// is unknown is not denied by it, and one whose field of an allow rule is unknown is not allowed by it.
+// Decrypted readings carry no manufacturer and type, they are matched with those of the meter's last telegram.
+type Filter struct {
+	Allow   []FilterRule `json:"allow,omitempty"`
+	Deny    []FilterRule `json:"deny,omitempty"`
+	MinRSSI *float64     `json:"min_rssi,omitempty"` // telegrams without rssi always pass
+}
+
+const (
+	FilterFieldMeterId      = "meter_id"
+	FilterFieldManufacturer = "manufacturer" // three letter code, e.g. KAM
+	FilterFieldType         = "type"         // medium, e.g. cold water
+	FilterFieldDriver       = "driver"
+)
+
+const (
+	FilterMatchExact  = "exact"
+	FilterMatchPrefix = "prefix"
+	FilterMatchRegex  = "regex"
+)
+
+type FilterRule struct {
+	Field string `json:"field"`
+	Match string `json:"match,omitempty"` // defaults to exact
+	Value string `json:"value"`
 }
 
 func New(path string) (*Config, error) {
 	cfg := Config{
-		LogLevel:              "debug",
-		WmbusLogFile:          "/logs/wmbusmeters.log",
-		WmbusMeterReadingsDir: "/logs/meter_readings",
-		LogBackupDir:          "/logs/backups",
-		SeekDir:               "/logs/seeks",
-		MqttConnStr:           "tcp://localhost:1883",
-		NimbusId:              "nimbus",
-		NimbusName:            "nimbus",
-		NimbusDeviceTypeId:    "urn:infai:ses:device-type:ae92bb03-fa0d-467e-8c4f-1892dd8494de",
+		LogLevel:                "debug",
+		WmbusLogFile:            "/logs/wmbusmeters.log",
+		WmbusMeterReadingsDir:   "/logs/meter_readings",
+		LogBackupDir:            "/logs/backups",
+		LogBackups:              2,
+		ArchiveDays:             30,
+		SeekDir:                 "/logs/seeks",
+		CheckpointFile:          "/logs/checkpoints.json",
+		CheckpointFlushInterval: sb_config_types.Duration(10 * time.Second),
+		MqttConnStr:             "tcp://localhost:1883",
+		NimbusId:                "nimbus",
+		NimbusName:              "nimbus",
+		NimbusDeviceTypeId:      "urn:infai:ses:device-type:ae92bb03-fa0d-467e-8c4f-1892dd8494de",
+		DeviceSync: DeviceSync{
+			Rate:          10,
+			MaxRetryDelay: sb_config_types.Duration(5 * time.Minute),
+		},
+		Pipeline: Pipeline{
+			Liveness: Liveness{
+				OfflineAfter:        sb_config_types.Duration(48 * time.Hour),
+				MissedTransmissions: 10,
+			},
+		},
 	}
 	err := sb_config_hdl.Load(&cfg, nil, envTypeParser, nil, path)
-	return &cfg, err
+	if err != nil {
+		return &cfg, err
+	}
+	cfg.setDefaults()
+	return &cfg, cfg.Validate()
+}
+
+func (c *Config) setDefaults() {
+	if len(c.Radios) == 0 {
+		c.Radios = []Radio{{
+			Id:           c.NimbusId,
+			Name:         c.NimbusName,
+			DeviceTypeId: c.NimbusDeviceTypeId,
+			Sources: []Source{
+				{Type: SourceTypeLog, Path: c.WmbusLogFile},
+				{Type: SourceTypeMeterReadings, Path: c.WmbusMeterReadingsDir},
+			},
+		}}
+	}
+	for i := range c.Radios {
+		if c.Radios[i].Name == "" {
+			c.Radios[i].Name = c.Radios[i].Id
+		}
+		if c.Radios[i].DeviceTypeId == "" {
+			c.Radios[i].DeviceTypeId = c.NimbusDeviceTypeId
+		}
+	}
+	if len(c.Sinks) == 0 {
+		c.Sinks = []Sink{{Type: SinkTypeMgw}}
+	}
+}
+
+// Annotation returns the local description of a meter, nil if it has none.
+func (m Meter) Annotation() *model.Annotation {
+	if m.Name == "" && m.Location == nil && len(m.Tags) == 0 {
+		return nil
+	}
+	return &model.Annotation{
+		Name:     m.Name,
+		Location: m.Location,
+		Tags:     m.Tags,
+	}
+}
+
+// Meter returns the meter definition with the given id.
+func (c *Config) Meter(id string) (Meter, bool) {
+	for _, m := range c.Meters {
+		if m.Id == id {
+			return m, true
+		}
+	}
+	return Meter{}, false
 }
@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Reloader holds the current configuration and replaces it when the config file changes or SIGHUP is received.
+// Only meters, sinks and pipeline options are applied live, other changes are reported and need a restart.
+type Reloader struct {
+	path      string
+	current   atomic.Pointer[Config]
+	listeners []func(old *Config, new *Config)
+	mux       sync.Mutex
+}
+
+func NewReloader(path string, cfg *Config) *Reloader {
+	r := &Reloader{path: path}
+	r.current.Store(cfg)
+	return r
+}
+
+func (r *Reloader) Get() *Config {
+	return r.current.Load()
+}
+
+// OnChange registers f to be called after a new configuration was applied.
+func (r *Reloader) OnChange(f func(old *Config, new *Config)) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.listeners = append(r.listeners, f)
+}
+
+// Start watches the config file and SIGHUP until ctx is done.
+func (r *Reloader) Start(ctx context.Context, wg *sync.WaitGroup) error {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+	var watchErrs chan error
+	var watcher *fsnotify.Watcher
+	if r.path != "" {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			signal.Stop(sighup)
+			return err
+		}
+		// watch the directory, editors and config management replace the file instead of writing it
+		err = watcher.Add(filepath.Dir(r.path))
+		if err != nil {
+			signal.Stop(sighup)
+			watcher.Close()
+			return err
+		}
+		events = watcher.Events
+		watchErrs = watcher.Errors
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer signal.Stop(sighup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+		debounce := time.NewTimer(0)
+		<-debounce.C
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sighup:
+				util.Logger.Info("caught SIGHUP, reloading config")
+				r.reload()
+			case event := <-events:
+				if filepath.Clean(event.Name) == filepath.Clean(r.path) && event.Op.Has(fsnotify.Write|fsnotify.Create) {
+					debounce.Reset(500 * time.Millisecond)
+				}
+			case err := <-watchErrs:
+				util.Logger.Warn("error while watching config file", "file", r.path, "err", err)
+			case <-debounce.C:
+				util.Logger.Info("config file changed, reloading config", "file", r.path)
+				r.reload()
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *Reloader) reload() {
+	err := r.Reload()
+	if err != nil {
+		util.Logger.Error("unable to reload config, keeping current config", "err", err)
+	}
+}
+
+// Reload reads and validates the configuration and applies it if it differs from the current one.
+func (r *Reloader) Reload() error {
+	next, err := New(r.path)
+	if err != nil {
+		return err
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	old := r.current.Load()
+	for _, field := range restartFields(old, next) {
+		util.Logger.Warn("config change requires a restart, keeping the current value until then", "field", field)
+	}
+	// keep what running components depend on
+	applied := *old
+	applied.Sinks = next.Sinks
+	applied.Meters = next.Meters
+	applied.Pipeline = next.Pipeline
+
+	changes := Diff(old, &applied)
+	if len(changes) == 0 {
+		util.Logger.Info("config reloaded, no applicable changes")
+		return nil
+	}
+	r.current.Store(&applied)
+	for _, change := range changes {
+		util.Logger.Info("config change applied", "change", change)
+	}
+	for _, f := range r.listeners {
+		f(old, &applied)
+	}
+	return nil
+}
+
+// Diff describes the live reloadable differences between old and new.
+func Diff(old *Config, new *Config) []string {
+	changes := []string{}
+	oldMeters := map[string]Meter{}
+	for _, m := range old.Meters {
+		oldMeters[m.Id] = m
+	}
+	newMeters := map[string]bool{}
+	for _, m := range new.Meters {
+		newMeters[m.Id] = true
+		o, ok := oldMeters[m.Id]
+		switch {
+		case !ok:
+			changes = append(changes, "meters: added "+m.Id)
+		case !reflect.DeepEqual(o, m):
+			changes = append(changes, "meters: changed "+m.Id)
+		}
+	}
+	for _, m := range old.Meters {
+		if !newMeters[m.Id] {
+			changes = append(changes, "meters: removed "+m.Id)
+		}
+	}
+	if !reflect.DeepEqual(old.Sinks, new.Sinks) {
+		changes = append(changes, "sinks: changed")
+	}
+	if !reflect.DeepEqual(old.Pipeline, new.Pipeline) {
+		changes = append(changes, "pipeline: changed")
+	}
+	return changes
+}
+
+func restartFields(old *Config, new *Config) []string {
+	fields := []string{}
+	ov := reflect.ValueOf(*old)
+	nv := reflect.ValueOf(*new)
+	for i := 0; i < ov.NumField(); i++ {
+		f := ov.Type().Field(i)
+		switch f.Name {
+		case "Sinks", "Meters", "Pipeline":
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			fields = append(fields, f.Tag.Get("json"))
+		}
+	}
+	return fields
+}
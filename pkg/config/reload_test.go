@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+func TestDiff(t *testing.T) {
+	meters := []Meter{{Id: "11111111", Name: "a"}, {Id: "22222222"}}
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string
+	}{
+		{"unchanged", func(c *Config) {}, []string{}},
+		{"meter added", func(c *Config) { c.Meters = append(c.Meters, Meter{Id: "33333333"}) }, []string{"meters: added 33333333"}},
+		{"meter removed", func(c *Config) { c.Meters = c.Meters[1:] }, []string{"meters: removed 11111111"}},
+		{"meter renamed", func(c *Config) { c.Meters[0].Name = "b" }, []string{"meters: changed 11111111"}},
+		{
+			name:   "meter location",
+			change: func(c *Config) { c.Meters[1].Location = &model.Location{Building: "B1"} },
+			want:   []string{"meters: changed 22222222"},
+		},
+		{"meters reordered", func(c *Config) { slices.Reverse(c.Meters) }, []string{}},
+		{"sinks", func(c *Config) { c.Sinks = []Sink{{Type: "stdout"}} }, []string{"sinks: changed"}},
+		{"pipeline", func(c *Config) { c.Pipeline.MeterDeviceTypeId = "other" }, []string{"pipeline: changed"}},
+		{"radios are not live", func(c *Config) { c.Radios = []Radio{{Id: "r2"}} }, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &Config{Meters: slices.Clone(meters)}
+			next := &Config{Meters: slices.Clone(meters)}
+			tt.change(next)
+			got := Diff(old, next)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"radios": [{"id": "r1", "sources": [{"type": "log", "path": "/a.log"}]}], "meters": [{"id": "11111111"}]}`)
+	cfg, err := New(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReloader(file, cfg)
+	calls := 0
+	r.OnChange(func(old *Config, new *Config) {
+		calls++
+		if old != cfg {
+			t.Error("listener not called with the previous config")
+		}
+	})
+
+	t.Run("meters and restart fields", func(t *testing.T) {
+		write(`{"radios": [{"id": "r1", "sources": [{"type": "log", "path": "/b.log"}]}], "meters": [{"id": "11111111", "name": "cold water"}]}`)
+		if err := r.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 1 {
+			t.Fatalf("listener called %d times, want 1", calls)
+		}
+		got := r.Get()
+		if m, _ := got.Meter("11111111"); m.Name != "cold water" {
+			t.Errorf("meter not applied: %+v", m)
+		}
+		if got.Radios[0].Sources[0].Path != "/a.log" {
+			t.Errorf("radios changed without restart: %+v", got.Radios)
+		}
+	})
+	t.Run("only restart fields", func(t *testing.T) {
+		write(`{"radios": [{"id": "r1", "sources": [{"type": "log", "path": "/c.log"}]}], "meters": [{"id": "11111111", "name": "cold water"}]}`)
+		if err := r.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 1 {
+			t.Errorf("listener called %d times, want 1", calls)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		before := r.Get()
+		write(`{"meters": [{"id": "nope"}]}`)
+		if err := r.Reload(); err == nil {
+			t.Fatal("invalid config reloaded")
+		}
+		if r.Get() != before {
+			t.Error("invalid config applied")
+		}
+	})
+}
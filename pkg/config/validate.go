@@ -0,0 +1,255 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // the container image has no zone database
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/units"
+)
+
+var meterIdPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}$`)
+var meterKeyPattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// ValidMeterId reports whether id is a wmbus meter id of 8 hex digits.
+func ValidMeterId(id string) bool {
+	return meterIdPattern.MatchString(id)
+}
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+var sourceTypes = []string{SourceTypeLog, SourceTypeMeterReadings, SourceTypeJsonStream, SourceTypeShell, SourceTypeMqtt}
+var sinkTypes = []string{SinkTypeMgw, SinkTypeStdout}
+var filterFields = []string{FilterFieldMeterId, FilterFieldManufacturer, FilterFieldType, FilterFieldDriver}
+var schemaTypes = []string{SchemaTypeNumber, SchemaTypeString, SchemaTypeBoolean}
+var filterMatches = []string{FilterMatchExact, FilterMatchPrefix, FilterMatchRegex}
+
+// Validate checks the configuration and returns all problems found, each prefixed with the path of the offending field.
+func (c *Config) Validate() error {
+	errs := []error{}
+	fail := func(field string, format string, a ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, a...)))
+	}
+
+	if !slices.Contains(logLevels, strings.ToLower(c.LogLevel)) {
+		fail("log_level", "unknown level %q, expected one of %s", c.LogLevel, strings.Join(logLevels, ", "))
+	}
+	if c.CheckpointFile == "" {
+		fail("checkpoint_file", "must not be empty")
+	}
+	if c.CheckpointFlushInterval <= 0 {
+		fail("checkpoint_flush_interval", "must be positive")
+	}
+	if c.DeviceSync.Rate <= 0 {
+		fail("device_sync.rate", "must be positive")
+	}
+	if c.DeviceSync.MaxRetryDelay <= 0 {
+		fail("device_sync.max_retry_delay", "must be positive")
+	}
+	if c.LogBackups < 1 {
+		fail("log_backups", "must be at least 1")
+	}
+	if c.ArchiveDays < 0 {
+		fail("archive_days", "must not be negative")
+	}
+
+	radioIds := map[string]int{}
+	for i, r := range c.Radios {
+		field := fmt.Sprintf("radios[%d]", i)
+		if r.Id == "" {
+			fail(field+".id", "must not be empty")
+		} else if j, ok := radioIds[r.Id]; ok {
+			fail(field+".id", "duplicate id %q, already used by radios[%d]", r.Id, j)
+		} else {
+			radioIds[r.Id] = i
+		}
+		if r.DeviceTypeId == "" {
+			fail(field+".device_type_id", "must not be empty")
+		}
+		if len(r.Sources) == 0 {
+			fail(field+".sources", "at least one source required")
+		}
+		for j, s := range r.Sources {
+			sField := fmt.Sprintf("%s.sources[%d]", field, j)
+			if !slices.Contains(sourceTypes, s.Type) {
+				fail(sField+".type", "unknown type %q, expected one of %s", s.Type, strings.Join(sourceTypes, ", "))
+			}
+			if s.Path == "" {
+				fail(sField+".path", "must not be empty")
+			}
+			if len(s.Fields) > 0 && (!slices.Contains(s.Fields, "id") || !slices.Contains(s.Fields, "name")) {
+				fail(sField+".fields", "must contain id and name")
+			}
+			if len([]rune(s.Separator)) > 1 {
+				fail(sField+".separator", "must be a single character")
+			}
+			if _, err := s.FileMode(); err != nil {
+				fail(sField+".mode", "%v", err)
+			}
+		}
+	}
+
+	for i, s := range c.Sinks {
+		if !slices.Contains(sinkTypes, s.Type) {
+			fail(fmt.Sprintf("sinks[%d].type", i), "unknown type %q, expected one of %s", s.Type, strings.Join(sinkTypes, ", "))
+		}
+	}
+
+	meterIds := map[string]int{}
+	for i, m := range c.Meters {
+		field := fmt.Sprintf("meters[%d]", i)
+		if !ValidMeterId(m.Id) {
+			fail(field+".id", "%q is not a wmbus meter id (8 hex digits)", m.Id)
+		} else if j, ok := meterIds[m.Id]; ok {
+			fail(field+".id", "duplicate id %q, already used by meters[%d]", m.Id, j)
+		} else {
+			meterIds[m.Id] = i
+		}
+		if m.ExpectedInterval < 0 {
+			fail(field+".expected_interval", "must not be negative")
+		}
+		if key := m.Key.Value(); key != "" && key != "NOKEY" && !meterKeyPattern.MatchString(key) {
+			fail(field+".key", "must be 32 hex characters or NOKEY")
+		}
+		if m.Aggregation != nil {
+			validateAggregation(field+".aggregation", *m.Aggregation, fail)
+		}
+	}
+	validateAggregation("pipeline.aggregation", c.Pipeline.Aggregation, fail)
+
+	if c.Pipeline.Liveness.OfflineAfter < 0 {
+		fail("pipeline.liveness.offline_after", "must not be negative")
+	}
+	if c.Pipeline.Liveness.MissedTransmissions < 0 {
+		fail("pipeline.liveness.missed_transmissions", "must not be negative")
+	}
+
+	for i, m := range c.Pipeline.Mappings {
+		field := fmt.Sprintf("pipeline.mappings[%d]", i)
+		if m.Field == "" {
+			fail(field+".field", "must not be empty")
+		}
+		if !m.Ignore && (m.Quantity == "" || m.Unit == "") {
+			fail(field, "quantity and unit required unless ignore is set")
+		}
+		if _, ok := c.Pipeline.Units[m.Quantity]; ok && !m.Ignore {
+			if uq, ok := units.Quantity(m.Unit); !ok || uq != m.Quantity {
+				fail(field+".unit", "%q is not a known %s unit, required to convert to pipeline.units.%s", m.Unit, m.Quantity, m.Quantity)
+			}
+		}
+	}
+
+	for i, schema := range c.Pipeline.Schemas {
+		field := fmt.Sprintf("pipeline.schemas[%d]", i)
+		if schema.Driver == "" {
+			fail(field+".driver", "must not be empty")
+		}
+		names := make([]string, 0, len(schema.Fields))
+		for name := range schema.Fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			f := schema.Fields[name]
+			fField := field + ".fields." + name
+			if !slices.Contains(schemaTypes, f.Type) {
+				fail(fField+".type", "unknown type %q, expected one of %s", f.Type, strings.Join(schemaTypes, ", "))
+			}
+			if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+				fail(fField, "min is greater than max")
+			}
+		}
+	}
+
+	quantities := make([]string, 0, len(c.Pipeline.Units))
+	for q := range c.Pipeline.Units {
+		quantities = append(quantities, q)
+	}
+	slices.Sort(quantities)
+	for _, q := range quantities {
+		unit := c.Pipeline.Units[q]
+		if uq, ok := units.Quantity(unit); !ok {
+			fail("pipeline.units."+q, "unknown unit %q", unit)
+		} else if uq != q {
+			fail("pipeline.units."+q, "unit %q measures %s", unit, uq)
+		}
+	}
+
+	quantities = quantities[:0]
+	for q := range c.Pipeline.Plausibility.MaxRates {
+		quantities = append(quantities, q)
+	}
+	slices.Sort(quantities)
+	for _, q := range quantities {
+		rate := c.Pipeline.Plausibility.MaxRates[q]
+		field := "pipeline.plausibility.max_rates." + q
+		if rate.Value <= 0 {
+			fail(field+".value", "must be positive")
+		}
+		if uq, ok := units.Quantity(rate.Unit); ok && uq != q {
+			fail(field+".unit", "unit %q measures %s", rate.Unit, uq)
+		}
+	}
+	if c.Pipeline.Plausibility.AcceptAfter < 0 {
+		fail("pipeline.plausibility.accept_after", "must not be negative")
+	}
+	for i, flag := range c.Pipeline.Plausibility.Withhold {
+		if !slices.Contains(plausibilityFlags, flag) {
+			fail(fmt.Sprintf("pipeline.plausibility.withhold[%d]", i), "unknown flag %q, expected one of %s", flag, strings.Join(plausibilityFlags, ", "))
+		}
+	}
+
+	if _, err := time.LoadLocation(c.Pipeline.Derived.Timezone); err != nil {
+		fail("pipeline.derived.timezone", "%v", err)
+	}
+
+	for _, list := range []struct {
+		name  string
+		rules []FilterRule
+	}{{"allow", c.Pipeline.Filter.Allow}, {"deny", c.Pipeline.Filter.Deny}} {
+		for i, r := range list.rules {
+			field := fmt.Sprintf("pipeline.filter.%s[%d]", list.name, i)
+			if !slices.Contains(filterFields, r.Field) {
+				fail(field+".field", "unknown field %q, expected one of %s", r.Field, strings.Join(filterFields, ", "))
+			}
+			if r.Match != "" && !slices.Contains(filterMatches, r.Match) {
+				fail(field+".match", "unknown match %q, expected one of %s", r.Match, strings.Join(filterMatches, ", "))
+			}
+			if r.Match == FilterMatchRegex {
+				if _, err := regexp.Compile(r.Value); err != nil {
+					fail(field+".value", "invalid regex: %s", err)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateAggregation(field string, a Aggregation, fail func(field string, format string, a ...any)) {
+	if a.Interval < 0 {
+		fail(field+".interval", "must not be negative")
+	}
+	if a.Function != "" && !slices.Contains(aggregateFunctions, a.Function) {
+		fail(field+".function", "unknown function %q, expected one of %s", a.Function, strings.Join(aggregateFunctions, ", "))
+	}
+}
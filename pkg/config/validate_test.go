@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// load writes a config file and loads it like the service does.
+func load(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return New(file)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errs   []string // expected parts of the error, valid if empty
+	}{
+		{"defaults", `{}`, nil},
+		{
+			name:   "radios and meters",
+			config: `{"radios": [{"id": "r1", "sources": [{"type": "log", "path": "/logs/wm.log"}, {"type": "shell", "path": "/run/wm.sock", "mode": "0660"}]}], "meters": [{"id": "1234abCD", "key": "NOKEY"}]}`,
+		},
+		{
+			name:   "invalid meter ids",
+			config: `{"meters": [{"id": "1234567"}, {"id": "1234567g"}, {"id": "12345678"}, {"id": "12345678"}]}`,
+			errs: []string{
+				`meters[0].id: "1234567" is not a wmbus meter id (8 hex digits)`,
+				`meters[1].id: "1234567g" is not a wmbus meter id (8 hex digits)`,
+				`meters[3].id: duplicate id "12345678", already used by meters[2]`,
+			},
+		},
+		{
+			name:   "invalid meter key",
+			config: `{"meters": [{"id": "12345678", "key": "0102"}]}`,
+			errs:   []string{"meters[0].key: must be 32 hex characters or NOKEY"},
+		},
+		{
+			name:   "invalid radios",
+			config: `{"radios": [{"id": "r1", "sources": []}, {"id": "r1", "sources": [{"type": "ftp", "path": ""}]}]}`,
+			errs: []string{
+				"radios[0].sources: at least one source required",
+				`radios[1].id: duplicate id "r1", already used by radios[0]`,
+				`radios[1].sources[0].type: unknown type "ftp"`,
+				"radios[1].sources[0].path: must not be empty",
+			},
+		},
+		{
+			name:   "invalid sources",
+			config: `{"radios": [{"id": "r1", "sources": [{"type": "meter_readings", "path": "/r", "fields": ["id", "total_m3"], "separator": ";;"}, {"type": "shell", "path": "/s", "mode": "0999"}]}]}`,
+			errs: []string{
+				"radios[0].sources[0].fields: must contain id and name",
+				"radios[0].sources[0].separator: must be a single character",
+				`radios[0].sources[1].mode: "0999" is not an octal file mode`,
+			},
+		},
+		{
+			name:   "invalid sinks",
+			config: `{"sinks": [{"type": "kafka"}]}`,
+			errs:   []string{`sinks[0].type: unknown type "kafka"`},
+		},
+		{
+			name:   "invalid device sync",
+			config: `{"device_sync": {"rate": 0, "max_retry_delay": "0s"}}`,
+			errs:   []string{"device_sync.rate: must be positive", "device_sync.max_retry_delay: must be positive"},
+		},
+		{
+			name:   "invalid pipeline",
+			config: `{"pipeline": {"units": {"volume": "kWh"}, "plausibility": {"withhold": ["odd"], "accept_after": -1}, "aggregation": {"function": "median"}, "derived": {"timezone": "Mars/Olympus"}}}`,
+			errs: []string{
+				`pipeline.units.volume: unit "kWh" measures energy`,
+				`pipeline.plausibility.withhold[0]: unknown flag "odd"`,
+				"pipeline.plausibility.accept_after: must not be negative",
+				`pipeline.aggregation.function`,
+				"pipeline.derived.timezone",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.config)
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %v", tt.errs)
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package derived
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/measurement"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/units"
+)
+
+const bucket = "derived"
+
+// rateUnits maps counter units to the unit of their average rate.
+var rateUnits = map[string]string{
+	"m3":  "m3/h",
+	"l":   "l/h",
+	"kWh": "kW",
+	"Wh":  "W",
+	"MWh": "MW",
+}
+
+// counter is the last reading of a counter and its consumption in the current day and month.
+// Complete is false while the day or month began before the first reading of the counter.
+type counter struct {
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit"`
+	Time          time.Time `json:"time"`
+	Day           float64   `json:"day"`
+	DayComplete   bool      `json:"day_complete"`
+	Month         float64   `json:"month"`
+	MonthComplete bool      `json:"month_complete"`
+}
+
+// Calculator derives consumption from consecutive readings of cumulative counters.
+type Calculator struct {
+	counters *checkpoint.Bucket[counter]
+	mux      sync.Mutex
+}
+
+// New creates a Calculator that persists its counters in store.
+func New(store *checkpoint.Store) *Calculator {
+	return &Calculator{counters: checkpoint.NewBucket[counter](store, bucket)}
+}
+
+// Derive computes the consumption of the current cumulative counters of r since their previous reading and the
+// totals of days and months completed since then, starting at midnight in loc. Consumption between readings is
+// spread evenly over the time between them. Decreasing counters, e.g. after a meter reset, count as no consumption.
+// Returns nil if nothing could be derived.
+func (c *Calculator) Derive(r *model.Reading, loc *time.Location) *model.Derived {
+	d := &model.Derived{
+		MeterId:    r.MeterId,
+		Timestamp:  r.Timestamp,
+		Annotation: r.Annotation,
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, m := range r.Measurements {
+		if m.StorageNumber != 0 || !measurement.Cumulative(m.Quantity) {
+			continue
+		}
+		t := time.Now()
+		if m.Timestamp != nil {
+			t = *m.Timestamp
+		}
+		key := r.MeterId + "/" + m.Field
+		prev, ok := c.counters.Get(key)
+		if ok && !t.After(prev.Time) {
+			// derived from a newer reading already
+			continue
+		}
+		next, ok := convert(prev, m.Unit, ok)
+		if !ok {
+			c.counters.Set(key, counter{Value: m.Value, Unit: m.Unit, Time: t})
+			continue
+		}
+
+		consumption := units.Round(m.Value - next.Value)
+		if consumption >= 0 {
+			interval := model.Interval{
+				Field:       m.Field,
+				Quantity:    m.Quantity,
+				Start:       prev.Time,
+				End:         t,
+				Consumption: consumption,
+				Unit:        m.Unit,
+			}
+			interval.Rate, interval.RateUnit = rate(consumption, m.Unit, t.Sub(prev.Time))
+			d.Intervals = append(d.Intervals, interval)
+		} else {
+			consumption = 0
+		}
+
+		seconds := t.Sub(prev.Time).Seconds()
+		from := prev.Time
+		for b := nextMidnight(from, loc); !b.After(t); b = nextMidnight(b, loc) {
+			share := consumption * b.Sub(from).Seconds() / seconds
+			next.Day += share
+			next.Month += share
+			if next.DayComplete {
+				d.Totals = append(d.Totals, total(m, model.PeriodDay, b.AddDate(0, 0, -1), b, next.Day))
+			}
+			next.Day, next.DayComplete = 0, true
+			if b.Day() == 1 {
+				if next.MonthComplete {
+					d.Totals = append(d.Totals, total(m, model.PeriodMonth, b.AddDate(0, -1, 0), b, next.Month))
+				}
+				next.Month, next.MonthComplete = 0, true
+			}
+			from = b
+		}
+		share := consumption * t.Sub(from).Seconds() / seconds
+		next.Day += share
+		next.Month += share
+		next.Value = m.Value
+		next.Time = t
+		c.counters.Set(key, next)
+	}
+	if len(d.Intervals) == 0 && len(d.Totals) == 0 {
+		return nil
+	}
+	return d
+}
+
+// convert returns prev in unit, false if there is no previous reading or its unit measures another quantity.
+func convert(prev counter, unit string, ok bool) (counter, bool) {
+	if !ok {
+		return prev, false
+	}
+	var err error
+	for _, v := range []*float64{&prev.Value, &prev.Day, &prev.Month} {
+		*v, err = units.Convert(*v, prev.Unit, unit)
+		if err != nil {
+			return prev, false
+		}
+	}
+	prev.Unit = unit
+	return prev, true
+}
+
+// rate returns the average flow or power of a consumption during d, nil if the unit has no rate.
+func rate(consumption float64, unit string, d time.Duration) (*float64, string) {
+	rateUnit, ok := rateUnits[unit]
+	if !ok {
+		q, _ := units.Quantity(unit)
+		if q != "energy" {
+			return nil, ""
+		}
+		var err error
+		consumption, err = units.Convert(consumption, unit, "kWh")
+		if err != nil {
+			return nil, ""
+		}
+		rateUnit = "kW"
+	}
+	r := units.Round(consumption / d.Hours())
+	return &r, rateUnit
+}
+
+func total(m model.Measurement, period string, start time.Time, end time.Time, consumption float64) model.Total {
+	return model.Total{
+		Field:       m.Field,
+		Quantity:    m.Quantity,
+		Period:      period,
+		Start:       start,
+		End:         end,
+		Consumption: units.Round(consumption),
+		Unit:        m.Unit,
+	}
+}
+
+func nextMidnight(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package derived
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+)
+
+func TestDerive(t *testing.T) {
+	at := func(day int, hour int) time.Time {
+		return time.Date(2024, 1, day, hour, 0, 0, 0, time.UTC)
+	}
+	rate := func(v float64) *float64 {
+		return &v
+	}
+	steps := []struct {
+		name  string
+		t     time.Time
+		value float64
+		unit  string
+		want  *model.Derived
+	}{
+		{"first reading", at(30, 22), 100, "m3", nil},
+		{"across midnight, first day incomplete", at(31, 2), 104, "m3", &model.Derived{
+			Intervals: []model.Interval{{Start: at(30, 22), End: at(31, 2), Consumption: 4, Rate: rate(1), RateUnit: "m3/h"}},
+		}},
+		{"day complete, first month incomplete", at(32, 0), 126, "m3", &model.Derived{
+			Intervals: []model.Interval{{Start: at(31, 2), End: at(32, 0), Consumption: 22, Rate: rate(1), RateUnit: "m3/h"}},
+			Totals:    []model.Total{{Period: model.PeriodDay, Start: at(31, 0), End: at(32, 0), Consumption: 24}},
+		}},
+		{"older reading", at(31, 12), 110, "m3", nil},
+		{"unit changed", at(32, 12), 138000, "l", &model.Derived{
+			Intervals: []model.Interval{{Start: at(32, 0), End: at(32, 12), Consumption: 12000, Unit: "l", Rate: rate(1000), RateUnit: "l/h"}},
+		}},
+		{"decrease", at(32, 18), 137000, "l", nil},
+		{"next day", at(33, 0), 137600, "l", &model.Derived{
+			Intervals: []model.Interval{{Start: at(32, 18), End: at(33, 0), Consumption: 600, Unit: "l", Rate: rate(100), RateUnit: "l/h"}},
+			Totals:    []model.Total{{Period: model.PeriodDay, Start: at(32, 0), End: at(33, 0), Consumption: 12600, Unit: "l"}},
+		}},
+	}
+	c := New(nil)
+	for _, step := range steps {
+		ts := step.t
+		r := &model.Reading{MeterId: "12345678", Timestamp: &ts, Measurements: []model.Measurement{
+			{Field: "total_m3", Quantity: "volume", Value: step.value, Unit: step.unit, Timestamp: &ts},
+			{Field: "flow_temperature_c", Quantity: "temperature", Value: 20, Unit: "°C", Timestamp: &ts},
+		}}
+		got := c.Derive(r, time.UTC)
+		want := step.want
+		if want != nil {
+			want.MeterId, want.Timestamp = "12345678", &ts
+			for i := range want.Intervals {
+				want.Intervals[i].Field, want.Intervals[i].Quantity = "total_m3", "volume"
+				if want.Intervals[i].Unit == "" {
+					want.Intervals[i].Unit = "m3"
+				}
+			}
+			for i := range want.Totals {
+				want.Totals[i].Field, want.Totals[i].Quantity = "total_m3", "volume"
+				if want.Totals[i].Unit == "" {
+					want.Totals[i].Unit = "m3"
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", step.name, got, want)
+		}
+	}
+}
+
+func TestDeriveTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(nil)
+	derive := func(t time.Time, value float64) *model.Derived {
+		return c.Derive(&model.Reading{MeterId: "12345678", Measurements: []model.Measurement{
+			{Field: "total_kwh", Quantity: "energy", Value: value, Unit: "kWh", Timestamp: &t},
+		}}, loc)
+	}
+	derive(time.Date(2024, 1, 1, 12, 0, 0, 0, loc), 0)
+	derive(time.Date(2024, 1, 2, 12, 0, 0, 0, loc), 24)
+	// midnight in Berlin is 23:00 UTC
+	d := derive(time.Date(2024, 1, 3, 12, 0, 0, 0, loc), 48)
+	if d == nil || len(d.Totals) != 1 {
+		t.Fatalf("got %+v, want one total", d)
+	}
+	total := d.Totals[0]
+	if !total.Start.Equal(time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)) || total.Consumption != 24 {
+		t.Errorf("got %+v, want 24 kWh from 2024-01-01T23:00Z", total)
+	}
+	if rate := d.Intervals[0].Rate; rate == nil || *rate != 1 || d.Intervals[0].RateUnit != "kW" {
+		t.Errorf("got rate %v %s, want 1 kW", rate, d.Intervals[0].RateUnit)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/filter"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const (
+	ListServiceId    = "discovery_list"
+	ApproveServiceId = "discovery_approve"
+	RejectServiceId  = "discovery_reject"
+)
+
+const (
+	pendingBucket   = "discovery_pending"
+	decisionsBucket = "discovery_decisions"
+)
+
+type decision string
+
+const (
+	approved decision = "approved"
+	rejected decision = "rejected"
+)
+
+// Observation is a telegram or reading of a meter.
+type Observation struct {
+	filter.Subject
+	Name  string
+	Radio string
+}
+
+// PendingMeter is a previously unseen meter awaiting approval by an operator.
+type PendingMeter struct {
+	Id           string    `json:"id"`
+	Name         string    `json:"name,omitempty"`
+	Manufacturer string    `json:"manufacturer,omitempty"`
+	Type         string    `json:"type,omitempty"`
+	Driver       string    `json:"driver,omitempty"`
+	Radio        string    `json:"radio,omitempty"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Count        int       `json:"count"`
+	RSSIMin      *float64  `json:"rssi_min,omitempty"`
+	RSSIMax      *float64  `json:"rssi_max,omitempty"`
+	RSSILast     *float64  `json:"rssi_last,omitempty"`
+}
+
+// Discovery collects unseen meters instead of letting them through. Pending meters and operator
+// decisions are kept in the checkpoint store.
+type Discovery struct {
+	store *checkpoint.Store
+	mux   sync.Mutex
+}
+
+func New(store *checkpoint.Store) *Discovery {
+	return &Discovery{store: store}
+}
+
+// Admit reports whether the observed meter was approved. Meters without decision are recorded as pending.
+func (d *Discovery) Admit(o Observation) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var dec decision
+	ok, err := d.store.Get(decisionsBucket, o.MeterId, &dec)
+	if err != nil {
+		util.Logger.Error("unable to read discovery decision", "meter_id", o.MeterId, "err", err)
+		return false
+	}
+	if ok {
+		return dec == approved
+	}
+
+	p := PendingMeter{}
+	ok, err = d.store.Get(pendingBucket, o.MeterId, &p)
+	if err != nil {
+		util.Logger.Error("unable to read pending meter", "meter_id", o.MeterId, "err", err)
+	}
+	now := time.Now()
+	if !ok {
+		util.Logger.Info("discovered new meter, awaiting approval", "meter_id", o.MeterId, "radio", o.Radio)
+		p = PendingMeter{Id: o.MeterId, FirstSeen: now}
+	}
+	p.LastSeen = now
+	p.Count++
+	p.Radio = o.Radio
+	p.Name = cmp.Or(o.Name, p.Name)
+	p.Manufacturer = cmp.Or(o.Manufacturer, p.Manufacturer)
+	p.Type = cmp.Or(o.Type, p.Type)
+	p.Driver = cmp.Or(o.Driver, p.Driver)
+	if o.RSSI != nil {
+		rssi := *o.RSSI
+		p.RSSILast = &rssi
+		if p.RSSIMin == nil || rssi < *p.RSSIMin {
+			p.RSSIMin = &rssi
+		}
+		if p.RSSIMax == nil || rssi > *p.RSSIMax {
+			p.RSSIMax = &rssi
+		}
+	}
+	err = d.store.Set(pendingBucket, o.MeterId, p)
+	if err != nil {
+		util.Logger.Error("unable to store pending meter", "meter_id", o.MeterId, "err", err)
+	}
+	return false
+}
+
+// Approved reports whether an operator approved the meter. Unlike Admit, unknown meters are not recorded.
+func (d *Discovery) Approved(id string) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var dec decision
+	ok, err := d.store.Get(decisionsBucket, id, &dec)
+	if err != nil {
+		util.Logger.Error("unable to read discovery decision", "meter_id", id, "err", err)
+		return false
+	}
+	return ok && dec == approved
+}
+
+// Pending returns all meters awaiting approval, most recently seen first.
+func (d *Discovery) Pending() []PendingMeter {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	result := []PendingMeter{}
+	for _, id := range d.store.Keys(pendingBucket) {
+		p := PendingMeter{}
+		ok, err := d.store.Get(pendingBucket, id, &p)
+		if err != nil || !ok {
+			continue
+		}
+		result = append(result, p)
+	}
+	slices.SortFunc(result, func(a, b PendingMeter) int {
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return result
+}
+
+// Approve lets the meter through. It is registered with its next telegram.
+func (d *Discovery) Approve(id string) error {
+	return d.decide(id, approved)
+}
+
+// Reject drops all further telegrams of the meter.
+func (d *Discovery) Reject(id string) error {
+	return d.decide(id, rejected)
+}
+
+func (d *Discovery) decide(id string, dec decision) error {
+	if id == "" {
+		return errors.New("missing meter id")
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	err := d.store.Set(decisionsBucket, id, dec)
+	if err != nil {
+		return err
+	}
+	d.store.Delete(pendingBucket, id)
+	util.Logger.Info("discovery decision", "meter_id", id, "decision", dec)
+	return nil
+}
+
+// HandleCommand executes a discovery command. Approve and reject expect the meter id as string
+// or as object with an id field.
+func (d *Discovery) HandleCommand(serviceId string, input any) (any, error) {
+	switch serviceId {
+	case ListServiceId:
+		return d.Pending(), nil
+	case ApproveServiceId, RejectServiceId:
+		id, err := idOf(input)
+		if err != nil {
+			return nil, err
+		}
+		if serviceId == ApproveServiceId {
+			err = d.Approve(id)
+		} else {
+			err = d.Reject(id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"id": id}, nil
+	default:
+		return nil, fmt.Errorf("unknown discovery command %q", serviceId)
+	}
+}
+
+func idOf(input any) (string, error) {
+	switch v := input.(type) {
+	case string:
+		return v, nil
+	case map[string]any:
+		if id, ok := v["id"].(string); ok {
+			return id, nil
+		}
+	}
+	return "", errors.New("expected meter id or object with id field")
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/filter"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+func openStore(t *testing.T, file string) (*checkpoint.Store, context.CancelFunc, *sync.WaitGroup) {
+	t.Helper()
+	ctx, cf := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	s, err := checkpoint.NewStore(file, time.Hour, ctx, wg)
+	if err != nil {
+		cf()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cf()
+		wg.Wait()
+	})
+	return s, cf, wg
+}
+
+func observation(id string, rssi float64) Observation {
+	return Observation{Subject: filter.Subject{MeterId: id, Manufacturer: "KAM", RSSI: &rssi}, Radio: "r1"}
+}
+
+func TestPending(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	s, _, _ := openStore(t, filepath.Join(t.TempDir(), "checkpoints.json"))
+	d := New(s)
+	if d.Admit(observation("11111111", -80)) {
+		t.Fatal("unseen meter admitted")
+	}
+	second := observation("11111111", -60)
+	second.Type = "cold water"
+	second.Manufacturer = ""
+	if d.Admit(second) {
+		t.Fatal("pending meter admitted")
+	}
+	d.Admit(observation("22222222", -70))
+
+	pending := d.Pending()
+	if len(pending) != 2 || pending[0].Id != "22222222" {
+		t.Fatalf("Pending() = %+v, want most recent first", pending)
+	}
+	p := pending[1]
+	if p.Count != 2 || p.Manufacturer != "KAM" || p.Type != "cold water" {
+		t.Errorf("pending meter not merged: %+v", p)
+	}
+	if *p.RSSIMin != -80 || *p.RSSIMax != -60 || *p.RSSILast != -60 {
+		t.Errorf("rssi = %v/%v/%v, want -80/-60/-60", *p.RSSIMin, *p.RSSIMax, *p.RSSILast)
+	}
+	if d.Approved("11111111") {
+		t.Error("pending meter reported as approved")
+	}
+}
+
+func TestDecisions(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	file := filepath.Join(t.TempDir(), "checkpoints.json")
+	s, cf, wg := openStore(t, file)
+	d := New(s)
+	for _, id := range []string{"11111111", "22222222", "33333333"} {
+		d.Admit(observation(id, -70))
+	}
+	if _, err := d.HandleCommand(ApproveServiceId, "11111111"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.HandleCommand(RejectServiceId, map[string]any{"id": "22222222"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.HandleCommand(ApproveServiceId, 42); err == nil {
+		t.Error("approved without meter id")
+	}
+	if _, err := d.HandleCommand("discovery_unknown", nil); err == nil {
+		t.Error("unknown command accepted")
+	}
+	check := func(d *Discovery) {
+		t.Helper()
+		if !d.Admit(observation("11111111", -70)) || !d.Approved("11111111") {
+			t.Error("approved meter not admitted")
+		}
+		if d.Admit(observation("22222222", -70)) || d.Approved("22222222") {
+			t.Error("rejected meter admitted")
+		}
+		list, err := d.HandleCommand(ListServiceId, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending := list.([]PendingMeter)
+		if len(pending) != 1 || pending[0].Id != "33333333" {
+			t.Errorf("pending = %+v, want only 33333333", pending)
+		}
+	}
+	check(d)
+
+	// decisions survive a restart
+	cf()
+	wg.Wait()
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	s, _, _ = openStore(t, file)
+	check(New(s))
+}
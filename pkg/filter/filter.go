@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/telegram"
+)
+
+// wmbusmeters logs the manufacturer as e.g. (KAM) Kamstrup Energi (0x2c2d)
+var manufacturerPattern = regexp.MustCompile(`^\(([A-Z@\[\\\]^_]{3})\)`)
+
+// wmbusmeters logs the type as e.g. Cold water meter (0x16)
+var typePattern = regexp.MustCompile(`\(0x([0-9a-fA-F]{2})\)$`)
+
+// Manufacturer returns the three letter code of a manufacturer as logged by wmbusmeters, e.g. KAM.
+// Codes are returned as they are.
+func Manufacturer(s string) string {
+	if m := manufacturerPattern.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return strings.TrimSpace(s)
+}
+
+// Type returns the medium of a device type as logged by wmbusmeters or the telegram decoder, e.g. cold water.
+func Type(s string) string {
+	m := typePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return strings.TrimSpace(s)
+	}
+	t, err := strconv.ParseUint(m[1], 16, 8)
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	return telegram.Media(byte(t))
+}
+
+// Subject holds the properties of a telegram or meter reading that rules can match on. Manufacturer and Type
+// have to be normalized by Manufacturer and Type, so that rules match the same way for all sources.
+// Empty properties never match a rule, so unknown meters are neither allowed nor denied by it.
+type Subject struct {
+	MeterId      string
+	Manufacturer string
+	Type         string
+	Driver       string
+	RSSI         *float64
+}
+
+type Filter struct {
+	allow   []rule
+	deny    []rule
+	minRSSI *float64
+}
+
+type rule struct {
+	config.FilterRule
+	regex *regexp.Regexp
+}
+
+func New(cfg config.Filter) (*Filter, error) {
+	f := &Filter{minRSSI: cfg.MinRSSI}
+	var err error
+	f.allow, err = compile(cfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	f.deny, err = compile(cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Allowed reports whether s may be forwarded. If not, the reason is returned as well.
+func (f *Filter) Allowed(s Subject) (bool, string) {
+	if f.minRSSI != nil && s.RSSI != nil && *s.RSSI < *f.minRSSI {
+		return false, "rssi below minimum"
+	}
+	for _, r := range f.deny {
+		if r.matches(s) {
+			return false, "denied by " + r.Field + " rule " + r.Value
+		}
+	}
+	if len(f.allow) == 0 {
+		return true, ""
+	}
+	for _, r := range f.allow {
+		if r.matches(s) {
+			return true, ""
+		}
+	}
+	return false, "not matched by any allow rule"
+}
+
+func compile(rules []config.FilterRule) ([]rule, error) {
+	result := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		c := rule{FilterRule: r}
+		if r.Match == config.FilterMatchRegex {
+			var err error
+			c.regex, err = regexp.Compile(r.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (r rule) value(s Subject) string {
+	switch r.Field {
+	case config.FilterFieldMeterId:
+		return s.MeterId
+	case config.FilterFieldManufacturer:
+		return s.Manufacturer
+	case config.FilterFieldType:
+		return s.Type
+	case config.FilterFieldDriver:
+		return s.Driver
+	}
+	return ""
+}
+
+func (r rule) matches(s Subject) bool {
+	value := r.value(s)
+	if value == "" {
+		return false
+	}
+	switch r.Match {
+	case config.FilterMatchPrefix:
+		return strings.HasPrefix(value, r.Value)
+	case config.FilterMatchRegex:
+		return r.regex.MatchString(value)
+	default:
+		return value == r.Value
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		fn   func(string) string
+		want string
+	}{
+		{"(KAM) Kamstrup Energi (0x2c2d)", Manufacturer, "KAM"},
+		{"KAM", Manufacturer, "KAM"},
+		{"Cold water meter (0x16)", Type, "cold water"},
+		{"water (0x07)", Type, "water"},
+		{"Water meter (0x07)", Type, "water"},
+		{"", Type, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	kam := Subject{MeterId: "12345678", Manufacturer: "KAM", Type: "cold water", Driver: "multical21"}
+	unknown := Subject{MeterId: "12345678", Driver: "multical21"}
+	tests := []struct {
+		name    string
+		cfg     config.Filter
+		subject Subject
+		want    bool
+	}{
+		{"no rules", config.Filter{}, unknown, true},
+		{"deny manufacturer", config.Filter{Deny: []config.FilterRule{{Field: config.FilterFieldManufacturer, Value: "KAM"}}}, kam, false},
+		{"deny manufacturer, unknown", config.Filter{Deny: []config.FilterRule{{Field: config.FilterFieldManufacturer, Value: "KAM"}}}, unknown, true},
+		{"deny type regex, unknown", config.Filter{Deny: []config.FilterRule{{Field: config.FilterFieldType, Match: config.FilterMatchRegex, Value: ".*"}}}, unknown, true},
+		{"deny other manufacturer", config.Filter{Deny: []config.FilterRule{{Field: config.FilterFieldManufacturer, Value: "APA"}}}, kam, true},
+		{"allow type", config.Filter{Allow: []config.FilterRule{{Field: config.FilterFieldType, Value: "cold water"}}}, kam, true},
+		{"allow type, unknown", config.Filter{Allow: []config.FilterRule{{Field: config.FilterFieldType, Value: "cold water"}}}, unknown, false},
+		{"allow id prefix", config.Filter{Allow: []config.FilterRule{{Field: config.FilterFieldMeterId, Match: config.FilterMatchPrefix, Value: "1234"}}}, unknown, true},
+		{"deny wins", config.Filter{
+			Allow: []config.FilterRule{{Field: config.FilterFieldMeterId, Match: config.FilterMatchRegex, Value: "^1"}},
+			Deny:  []config.FilterRule{{Field: config.FilterFieldDriver, Value: "multical21"}},
+		}, kam, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, reason := f.Allowed(tt.subject); got != tt.want {
+				t.Errorf("Allowed() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logrotate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nxadm/tail"
+)
+
+// FingerprintSize is the number of leading bytes hashed to recognize a file's content.
+const FingerprintSize = 1024
+
+// FileId identifies a file independently of its name.
+type FileId struct {
+	Inode          uint64 `json:"inode,omitempty"`
+	Device         uint64 `json:"device,omitempty"`
+	Fingerprint    string `json:"fingerprint,omitempty"`
+	FingerprintLen int64  `json:"fingerprint_len,omitempty"`
+}
+
+// Checkpoint is the persisted read position of a file together with the identity of the file it was taken on.
+type Checkpoint struct {
+	tail.SeekInfo
+	FileId
+	Time time.Time `json:"time"`
+}
+
+type Rotation string
+
+const (
+	NotRotated   Rotation = ""
+	CopyTruncate Rotation = "copytruncate"
+	Renamed      Rotation = "rename"
+	Recreated    Rotation = "recreate"
+)
+
+// Identify returns the FileId of file. The fingerprint covers the first min(size, FingerprintSize) bytes.
+func Identify(file string) (FileId, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return FileId{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return FileId{}, err
+	}
+	id := FileId{}
+	id.Inode, id.Device = inodeOf(info)
+	id.Fingerprint, id.FingerprintLen, err = fingerprint(f, FingerprintSize)
+	return id, err
+}
+
+// Detect compares the current state of file with the checkpoint cp and reports how the file was rotated since.
+// For renamed files the new location of the checkpointed file is returned, if it could be found in file's
+// directory or one of searchDirs.
+func Detect(file string, cp Checkpoint, searchDirs ...string) (Rotation, string, error) {
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		moved := findByInode(cp.FileId, append([]string{filepath.Dir(file)}, searchDirs...))
+		if moved != "" {
+			return Renamed, moved, nil
+		}
+		return Recreated, "", nil
+	}
+	if err != nil {
+		return NotRotated, "", err
+	}
+	inode, device := inodeOf(info)
+	if cp.Inode != 0 && (cp.Inode != inode || cp.Device != device) {
+		moved := findByInode(cp.FileId, append([]string{filepath.Dir(file)}, searchDirs...))
+		if moved != "" {
+			return Renamed, moved, nil
+		}
+		return Recreated, "", nil
+	}
+	if info.Size() < cp.Offset {
+		return CopyTruncate, "", nil
+	}
+	if cp.Fingerprint != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return NotRotated, "", err
+		}
+		defer f.Close()
+		fp, _, err := fingerprint(f, cp.FingerprintLen)
+		if err != nil {
+			return NotRotated, "", err
+		}
+		if fp != cp.Fingerprint {
+			// same inode, different content: truncated and written past the old offset again
+			return CopyTruncate, "", nil
+		}
+	}
+	return NotRotated, "", nil
+}
+
+// Matches reports whether file still starts with the content fingerprinted in id.
+func Matches(file string, id FileId) bool {
+	if id.Fingerprint == "" {
+		return false
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fp, n, err := fingerprint(f, id.FingerprintLen)
+	return err == nil && n == id.FingerprintLen && fp == id.Fingerprint
+}
+
+func findByInode(id FileId, dirs []string) string {
+	if id.Inode == 0 {
+		return ""
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			inode, device := inodeOf(info)
+			if inode == id.Inode && device == id.Device {
+				return filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	return ""
+}
+
+func fingerprint(r io.Reader, n int64) (string, int64, error) {
+	h := sha256.New()
+	read, err := io.Copy(h, io.LimitReader(r, n))
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), read, nil
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logrotate
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/nxadm/tail"
+)
+
+func TestDetect(t *testing.T) {
+	// checkpoint returns a checkpoint at offset of the file as it is now
+	checkpoint := func(t *testing.T, file string, offset int64) Checkpoint {
+		t.Helper()
+		id, err := Identify(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Checkpoint{SeekInfo: tail.SeekInfo{Offset: offset}, FileId: id}
+	}
+	write := func(t *testing.T, file string, content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, file string) Checkpoint
+		want  Rotation
+		moved string // base name of the file the checkpoint was taken on, if renamed
+	}{
+		{
+			name: "appended",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\n")
+				cp := checkpoint(t, file, 6)
+				write(t, file, "first\nsecond\n")
+				return cp
+			},
+			want: NotRotated,
+		},
+		{
+			name: "truncated",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\nsecond\n")
+				cp := checkpoint(t, file, 13)
+				write(t, file, "new\n")
+				return cp
+			},
+			want: CopyTruncate,
+		},
+		{
+			name: "truncated and written past the offset",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\n")
+				cp := checkpoint(t, file, 6)
+				write(t, file, "other content\n")
+				return cp
+			},
+			want: CopyTruncate,
+		},
+		{
+			name: "renamed and recreated",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\n")
+				cp := checkpoint(t, file, 6)
+				if err := os.Rename(file, file+".1"); err != nil {
+					t.Fatal(err)
+				}
+				write(t, file, "new\n")
+				return cp
+			},
+			want:  Renamed,
+			moved: "wm.log.1",
+		},
+		{
+			name: "renamed, not recreated yet",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\n")
+				cp := checkpoint(t, file, 6)
+				if err := os.Rename(file, file+".1"); err != nil {
+					t.Fatal(err)
+				}
+				return cp
+			},
+			want:  Renamed,
+			moved: "wm.log.1",
+		},
+		{
+			name: "removed and recreated",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\n")
+				cp := checkpoint(t, file, 6)
+				if err := os.Remove(file); err != nil {
+					t.Fatal(err)
+				}
+				write(t, file, "first\nsecond\n")
+				// the file system may reuse the inode for the new file
+				cp.Inode = math.MaxUint64
+				return cp
+			},
+			want: Recreated,
+		},
+		{
+			name: "checkpoint without file id",
+			setup: func(t *testing.T, file string) Checkpoint {
+				write(t, file, "first\n")
+				return Checkpoint{SeekInfo: tail.SeekInfo{Offset: 6}}
+			},
+			want: NotRotated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "wm.log")
+			cp := tt.setup(t, file)
+			if tt.want == Renamed && cp.Inode == 0 {
+				t.Skip("inodes are not supported on " + runtime.GOOS)
+			}
+			got, moved, err := Detect(file, cp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("rotation %q, want %q", got, tt.want)
+			}
+			if tt.moved != "" && filepath.Base(moved) != tt.moved {
+				t.Errorf("moved to %q, want %q", moved, tt.moved)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "wm.log")
+	if err := os.WriteFile(file, []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	id, err := Identify(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.FingerprintLen != 6 || id.Fingerprint == "" {
+		t.Fatalf("id %+v, want fingerprint of 6 bytes", id)
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"same content", "first\n", true},
+		{"appended", "first\nsecond\n", true},
+		{"different content", "other\n", false},
+		{"shorter", "fir", false},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := Matches(file, id); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if Matches(file, FileId{}) {
+		t.Error("empty file id matches")
+	}
+	if Matches(filepath.Join(dir, "missing"), id) {
+		t.Error("missing file matches")
+	}
+}
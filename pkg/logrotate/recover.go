@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logrotate
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// Segment is the part of a rotated file starting at Offset that has not been read yet.
+type Segment struct {
+	File   string
+	Offset int64
+}
+
+type backup struct {
+	file    string
+	modTime time.Time
+	id      FileId
+}
+
+// FindUnread returns the unread segments of rotated copies of file, oldest first. Candidates are the backups
+// named <name>.<n> in file's directory and in dirs, plus moved if the checkpointed file was renamed.
+// Only candidates modified after the checkpoint are considered. The oldest one matching the checkpoint's
+// identity is read from the checkpoint offset, all newer ones completely. If none matches, nothing is read, as
+// the candidates can not be told apart from backups read before.
+func FindUnread(file string, cp Checkpoint, moved string, dirs ...string) ([]Segment, error) {
+	name := filepath.Base(file)
+	candidates := []backup{}
+	seen := map[string]bool{}
+	add := func(f string) error {
+		if f == "" || f == file || seen[f] {
+			return nil
+		}
+		seen[f] = true
+		info, err := os.Stat(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.ModTime().After(cp.Time) {
+			// not written since the checkpoint, so there is nothing unread in it
+			return nil
+		}
+		id, err := Identify(f)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, backup{file: f, modTime: info.ModTime(), id: id})
+		return nil
+	}
+	if err := add(moved); err != nil {
+		return nil, err
+	}
+	for _, dir := range append([]string{filepath.Dir(file)}, dirs...) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			suffix, ok := strings.CutPrefix(entry.Name(), name+".")
+			if entry.IsDir() || !ok || !isNumber(suffix) {
+				continue
+			}
+			if err = add(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b backup) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	match := slices.IndexFunc(candidates, func(b backup) bool {
+		if cp.Inode != 0 && b.id.Inode == cp.Inode && b.id.Device == cp.Device {
+			return true
+		}
+		return Matches(b.file, cp.FileId)
+	})
+	segments := []Segment{}
+	if match == -1 {
+		if len(candidates) > 0 {
+			util.Logger.Warn("no rotated copy matches the checkpoint, skipping unread data of rotated copies", "file", file, "candidates", len(candidates))
+		}
+		return segments, nil
+	}
+	for i, b := range candidates {
+		switch {
+		case i == match:
+			segments = append(segments, Segment{File: b.file, Offset: cp.Offset})
+		case i > match:
+			segments = append(segments, Segment{File: b.file})
+		}
+	}
+	return segments, nil
+}
+
+// ReadSegment calls handle for every line of the segment.
+func ReadSegment(seg Segment, handle func(text string)) error {
+	f, err := os.Open(seg.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Seek(seg.Offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		handle(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logrotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+	"github.com/nxadm/tail"
+)
+
+func TestFindUnread(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	cpTime := time.Now().Add(-time.Hour)
+	write := func(t *testing.T, file string, content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string) Checkpoint
+		want  []Segment
+	}{
+		{
+			name: "matching backup from offset, older backups skipped",
+			setup: func(t *testing.T, dir string) Checkpoint {
+				write(t, filepath.Join(dir, "wm.log.2"), "read long ago\n", cpTime.Add(-24*time.Hour))
+				write(t, filepath.Join(dir, "wm.log.1"), "checkpointed\nunread\n", cpTime.Add(time.Minute))
+				id, err := Identify(filepath.Join(dir, "wm.log.1"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return Checkpoint{SeekInfo: tail.SeekInfo{Offset: 13}, FileId: FileId{Fingerprint: id.Fingerprint, FingerprintLen: id.FingerprintLen}, Time: cpTime}
+			},
+			want: []Segment{{File: "wm.log.1", Offset: 13}},
+		},
+		{
+			name: "backups newer than the match read completely",
+			setup: func(t *testing.T, dir string) Checkpoint {
+				write(t, filepath.Join(dir, "wm.log.2"), "checkpointed\nunread\n", cpTime.Add(time.Minute))
+				write(t, filepath.Join(dir, "wm.log.1"), "newer\n", cpTime.Add(2*time.Minute))
+				id, err := Identify(filepath.Join(dir, "wm.log.2"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return Checkpoint{SeekInfo: tail.SeekInfo{Offset: 13}, FileId: FileId{Fingerprint: id.Fingerprint, FingerprintLen: id.FingerprintLen}, Time: cpTime}
+			},
+			want: []Segment{{File: "wm.log.2", Offset: 13}, {File: "wm.log.1"}},
+		},
+		{
+			name: "no match reads nothing",
+			setup: func(t *testing.T, dir string) Checkpoint {
+				write(t, filepath.Join(dir, "wm.log.1"), "unrelated\n", cpTime.Add(time.Minute))
+				return Checkpoint{SeekInfo: tail.SeekInfo{Offset: 13}, FileId: FileId{Fingerprint: "00", FingerprintLen: 13}, Time: cpTime}
+			},
+			want: []Segment{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "wm.log")
+			write(t, file, "", time.Now())
+			cp := tt.setup(t, dir)
+			got, err := FindUnread(file, cp, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				got[i].File = filepath.Base(got[i].File)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FindUnread() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
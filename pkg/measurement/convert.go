@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package measurement
+
+import (
+	"fmt"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/units"
+)
+
+// Convert converts the measurements of r to the target unit of their quantity, given as quantity to unit.
+// Measurements that can not be converted keep their unit and are reported in the returned errors.
+func Convert(r *model.Reading, targets map[string]string) []error {
+	errs := []error{}
+	for i, m := range r.Measurements {
+		target, ok := targets[m.Quantity]
+		if !ok || target == m.Unit {
+			continue
+		}
+		value, err := units.Convert(m.Value, m.Unit, target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Field, err))
+			continue
+		}
+		r.Measurements[i].Value = value
+		r.Measurements[i].Unit = target
+	}
+	return errs
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package measurement
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+)
+
+// unitSuffix maps the unit suffix of wmbusmeters field names to quantity and unit.
+// Longer suffixes come first, so that e.g. _kwh is not taken for _h.
+type unitSuffix struct {
+	suffix   string
+	quantity string
+	unit     string
+}
+
+var unitSuffixes = []unitSuffix{
+	{"_m3h", "volume_flow", "m3/h"},
+	{"_lh", "volume_flow", "l/h"},
+	{"_m3", "volume", "m3"},
+	{"_l", "volume", "l"},
+	{"_kwh", "energy", "kWh"},
+	{"_mwh", "energy", "MWh"},
+	{"_wh", "energy", "Wh"},
+	{"_gj", "energy", "GJ"},
+	{"_mj", "energy", "MJ"},
+	{"_kw", "power", "kW"},
+	{"_w", "power", "W"},
+	{"_c", "temperature", "°C"},
+	{"_k", "temperature_difference", "K"}, // wmbusmeters uses K only for differences, e.g. temperature_difference_k
+	{"_f", "temperature", "°F"},
+	{"_hca", "heat_cost_allocation", "hca"},
+	{"_rh", "relative_humidity", "%RH"},
+	{"_pct", "ratio", "%"},
+	{"_v", "voltage", "V"},
+	{"_a", "current", "A"},
+	{"_bar", "pressure", "bar"},
+	{"_pa", "pressure", "Pa"},
+	{"_h", "duration", "h"},
+	{"_s", "duration", "s"},
+	{"_counter", "count", ""},
+}
+
+// quantities of cumulative counters, e.g. volume totals
+var cumulative = []string{"volume", "energy", "heat_cost_allocation", "count"}
+
+// Cumulative reports whether measurements of quantity are counters that only increase.
+func Cumulative(quantity string) bool {
+	return slices.Contains(cumulative, quantity)
+}
+
+// fields describing the reading instead of the meter's values
+var metaFields = []string{"_", "id", "name", "media", "meter", "driver", "timestamp", "device", "rssi_dbm"}
+
+var tariffPattern = regexp.MustCompile(`(?:^|_)tariff_?(\d+)(?:_|$)`)
+var storagePattern = regexp.MustCompile(`(?:^|_)(?:history|storage)_?(\d+)(?:_|$)`)
+
+var dateLayouts = []string{time.DateTime, "2006-01-02 15:04", time.DateOnly}
+
+// fields in which drivers report the meter's clock, in order of preference
+var meterTimeFields = []string{"meter_datetime", "meter_date_time", "device_date_time", "device_datetime"}
+
+// Map creates a normalized reading from a wmbusmeters JSON reading. Fields are mapped by their unit
+// suffix unless mappings contain a rule for them. Fields without known unit are skipped.
+func Map(j map[string]any, mappings []config.Mapping) *model.Reading {
+	r := &model.Reading{
+		MeterId:      stringField(j, "id"),
+		Driver:       stringField(j, "driver"),
+		Media:        stringField(j, "media"),
+		Measurements: []model.Measurement{},
+	}
+	if r.Driver == "" {
+		r.Driver = stringField(j, "meter")
+	}
+	if ts, err := time.Parse(time.RFC3339, stringField(j, "timestamp")); err == nil {
+		r.Timestamp = &ts
+	}
+	for _, field := range meterTimeFields {
+		if ts := parseDate(stringField(j, field)); ts != nil {
+			r.MeterTime = ts
+			break
+		}
+	}
+
+	fields := make([]string, 0, len(j))
+	for field := range j {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		if slices.Contains(metaFields, field) {
+			continue
+		}
+		value, ok := j[field].(float64)
+		if !ok {
+			continue
+		}
+		m, ok := mapField(r.Driver, field, mappings)
+		if !ok {
+			continue
+		}
+		m.Value = value
+		m.Timestamp = r.Timestamp
+		if ts := dateOf(j, m.Name); ts != nil {
+			m.Timestamp = ts
+		}
+		r.Measurements = append(r.Measurements, m)
+	}
+	return r
+}
+
+func mapField(driver string, field string, mappings []config.Mapping) (model.Measurement, bool) {
+	for _, mapping := range mappings {
+		if mapping.Field != field || (mapping.Driver != "" && mapping.Driver != driver) {
+			continue
+		}
+		if mapping.Ignore {
+			return model.Measurement{}, false
+		}
+		return model.Measurement{
+			Field:         field,
+			Name:          nameOf(field),
+			Quantity:      mapping.Quantity,
+			Unit:          mapping.Unit,
+			Tariff:        mapping.Tariff,
+			StorageNumber: mapping.StorageNumber,
+		}, true
+	}
+	for _, u := range unitSuffixes {
+		name, ok := strings.CutSuffix(field, u.suffix)
+		if !ok || name == "" {
+			continue
+		}
+		return model.Measurement{
+			Field:         field,
+			Name:          name,
+			Quantity:      u.quantity,
+			Unit:          u.unit,
+			Tariff:        numberToken(tariffPattern, name),
+			StorageNumber: storageOf(name),
+		}, true
+	}
+	return model.Measurement{}, false
+}
+
+func nameOf(field string) string {
+	for _, u := range unitSuffixes {
+		if name, ok := strings.CutSuffix(field, u.suffix); ok && name != "" {
+			return name
+		}
+	}
+	return field
+}
+
+// storageOf derives the storage number from the field name. Values at the set date or of the previous
+// period, which wmbusmeters names target or prev, are storage number 1.
+func storageOf(name string) int {
+	if n := numberToken(storagePattern, name); n > 0 {
+		return n
+	}
+	if strings.HasPrefix(name, "target") || strings.Contains(name, "at_set_date") || strings.Contains(name, "prev") {
+		return 1
+	}
+	return 0
+}
+
+func numberToken(pattern *regexp.Regexp, name string) int {
+	match := pattern.FindStringSubmatch(name)
+	if match == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(match[1])
+	return n
+}
+
+// dateOf returns the date wmbusmeters reports for a stored value, e.g. target_date for target_m3.
+func dateOf(j map[string]any, name string) *time.Time {
+	candidates := []string{name + "_date", name + "_datetime"}
+	if strings.Contains(name, "at_set_date") {
+		candidates = append(candidates, "set_date")
+	}
+	for _, c := range candidates {
+		if ts := parseDate(stringField(j, c)); ts != nil {
+			return ts
+		}
+	}
+	return nil
+}
+
+// parseDate parses the local date formats of wmbusmeters, nil if s is none of them.
+func parseDate(s string) *time.Time {
+	for _, layout := range dateLayouts {
+		if ts, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &ts
+		}
+	}
+	return nil
+}
+
+func stringField(j map[string]any, key string) string {
+	s, _ := j[key].(string)
+	return s
+}
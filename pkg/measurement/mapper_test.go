@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package measurement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+)
+
+func TestMapFields(t *testing.T) {
+	tests := []struct {
+		field    string
+		mappings []config.Mapping
+		want     *model.Measurement // nil if the field is skipped
+	}{
+		{"total_m3", nil, &model.Measurement{Name: "total", Quantity: "volume", Unit: "m3"}},
+		{"total_energy_consumption_kwh", nil, &model.Measurement{Name: "total_energy_consumption", Quantity: "energy", Unit: "kWh"}},
+		{"current_power_consumption_kw", nil, &model.Measurement{Name: "current_power_consumption", Quantity: "power", Unit: "kW"}},
+		{"volume_flow_m3h", nil, &model.Measurement{Name: "volume_flow", Quantity: "volume_flow", Unit: "m3/h"}},
+		{"flow_temperature_c", nil, &model.Measurement{Name: "flow_temperature", Quantity: "temperature", Unit: "°C"}},
+		{"temperature_difference_k", nil, &model.Measurement{Name: "temperature_difference", Quantity: "temperature_difference", Unit: "K"}},
+		{"operating_time_h", nil, &model.Measurement{Name: "operating_time", Quantity: "duration", Unit: "h"}},
+		{"total_tariff2_kwh", nil, &model.Measurement{Name: "total_tariff2", Quantity: "energy", Unit: "kWh", Tariff: 2}},
+		{"consumption_tariff_1_m3", nil, &model.Measurement{Name: "consumption_tariff_1", Quantity: "volume", Unit: "m3", Tariff: 1}},
+		{"history_3_hca", nil, &model.Measurement{Name: "history_3", Quantity: "heat_cost_allocation", Unit: "hca", StorageNumber: 3}},
+		{"target_m3", nil, &model.Measurement{Name: "target", Quantity: "volume", Unit: "m3", StorageNumber: 1}},
+		{"consumption_at_set_date_kwh", nil, &model.Measurement{Name: "consumption_at_set_date", Quantity: "energy", Unit: "kWh", StorageNumber: 1}},
+		{"status_flags", nil, nil},
+		{"_m3", nil, nil},
+		{
+			field:    "flow",
+			mappings: []config.Mapping{{Field: "flow", Quantity: "volume_flow", Unit: "l/h", Tariff: 1, StorageNumber: 2}},
+			want:     &model.Measurement{Name: "flow", Quantity: "volume_flow", Unit: "l/h", Tariff: 1, StorageNumber: 2},
+		},
+		{
+			field:    "total_m3",
+			mappings: []config.Mapping{{Field: "total_m3", Quantity: "volume", Unit: "l"}},
+			want:     &model.Measurement{Name: "total", Quantity: "volume", Unit: "l"},
+		},
+		{
+			field:    "total_m3",
+			mappings: []config.Mapping{{Driver: "multical21", Field: "total_m3", Quantity: "volume", Unit: "l"}},
+			want:     &model.Measurement{Name: "total", Quantity: "volume", Unit: "l"},
+		},
+		{
+			field:    "total_m3",
+			mappings: []config.Mapping{{Driver: "izar", Field: "total_m3", Quantity: "volume", Unit: "l"}},
+			want:     &model.Measurement{Name: "total", Quantity: "volume", Unit: "m3"},
+		},
+		{"total_m3", []config.Mapping{{Field: "total_m3", Ignore: true}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			r := Map(map[string]any{"id": "12345678", "driver": "multical21", tt.field: 1.5}, tt.mappings)
+			if tt.want == nil {
+				if len(r.Measurements) != 0 {
+					t.Fatalf("field mapped to %+v", r.Measurements)
+				}
+				return
+			}
+			if len(r.Measurements) != 1 {
+				t.Fatalf("got %d measurements, want 1", len(r.Measurements))
+			}
+			want := *tt.want
+			want.Field = tt.field
+			want.Value = 1.5
+			if got := r.Measurements[0]; got.Field != want.Field || got.Name != want.Name || got.Quantity != want.Quantity ||
+				got.Unit != want.Unit || got.Value != want.Value || got.Tariff != want.Tariff || got.StorageNumber != want.StorageNumber {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMapReading(t *testing.T) {
+	j := map[string]any{
+		"_":              "telegram",
+		"media":          "cold water",
+		"meter":          "multical21",
+		"name":           "kitchen",
+		"id":             "12345678",
+		"rssi_dbm":       -70.0,
+		"total_m3":       6.408,
+		"target_m3":      6.1,
+		"target_date":    "2025-01-31",
+		"status":         "OK",
+		"meter_datetime": "2025-02-03 10:15",
+		"timestamp":      "2025-02-03T09:16:00Z",
+	}
+	r := Map(j, nil)
+	if r.MeterId != "12345678" || r.Driver != "multical21" || r.Media != "cold water" {
+		t.Errorf("reading = %+v", r)
+	}
+	ts := time.Date(2025, 2, 3, 9, 16, 0, 0, time.UTC)
+	if r.Timestamp == nil || !r.Timestamp.Equal(ts) {
+		t.Errorf("timestamp = %v, want %v", r.Timestamp, ts)
+	}
+	meterTime := time.Date(2025, 2, 3, 10, 15, 0, 0, time.Local)
+	if r.MeterTime == nil || !r.MeterTime.Equal(meterTime) {
+		t.Errorf("meter time = %v, want %v", r.MeterTime, meterTime)
+	}
+	if len(r.Measurements) != 2 || r.Measurements[0].Field != "target_m3" || r.Measurements[1].Field != "total_m3" {
+		t.Fatalf("measurements = %+v, want target_m3 and total_m3", r.Measurements)
+	}
+	target := time.Date(2025, 1, 31, 0, 0, 0, 0, time.Local)
+	if ts := r.Measurements[0].Timestamp; ts == nil || !ts.Equal(target) {
+		t.Errorf("target timestamp = %v, want %v", ts, target)
+	}
+	if ts := r.Measurements[1].Timestamp; ts == nil || !ts.Equal(*r.Timestamp) {
+		t.Errorf("total timestamp = %v, want reading timestamp", ts)
+	}
+}
+
+func TestMapMeterTime(t *testing.T) {
+	tests := []struct {
+		name string
+		j    map[string]any
+		want *time.Time
+	}{
+		{"none", map[string]any{}, nil},
+		{"date time", map[string]any{"meter_datetime": "2025-02-03 10:15:30"}, ptr(time.Date(2025, 2, 3, 10, 15, 30, 0, time.Local))},
+		{"date only", map[string]any{"device_date_time": "2025-02-03"}, ptr(time.Date(2025, 2, 3, 0, 0, 0, 0, time.Local))},
+		{"preferred field", map[string]any{"device_datetime": "2025-01-01 00:00", "meter_date_time": "2025-02-03 10:15"}, ptr(time.Date(2025, 2, 3, 10, 15, 0, 0, time.Local))},
+		{"invalid", map[string]any{"meter_datetime": "03.02.2025"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Map(tt.j, nil).MeterTime
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("meter time = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -16,6 +16,8 @@
 
 package model
 
+import "time"
+
 type EncryptedMessage struct {
 	Telegram     string  `json:"telegram,omitempty"`
 	Manufacturer string  `json:"manufacturer,omitempty"`
@@ -26,4 +28,117 @@ type EncryptedMessage struct {
 	RSSIUnit     string  `json:"rssi_unit,omitempty"`
 	Device       string  `json:"device,omitempty"`
 	Driver       string  `json:"driver,omitempty"`
+
+	Timestamp  *time.Time  `json:"timestamp,omitempty"` // gateway receive time
+	Annotation *Annotation `json:"annotation,omitempty"`
+}
+
+// Diagnostic is a problem reported by wmbusmeters or found while reading its output.
+type Diagnostic struct {
+	Type      string     `json:"type"`
+	Severity  string     `json:"severity"`
+	MeterId   string     `json:"meter_id,omitempty"`
+	Message   string     `json:"message"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+const (
+	AlertRaised  = "raised"
+	AlertCleared = "cleared"
+)
+
+// Alert reports that an alarm of a meter, e.g. a leak or low battery, was raised or cleared.
+type Alert struct {
+	MeterId    string      `json:"meter_id"`
+	Type       string      `json:"type"`
+	Severity   string      `json:"severity"`
+	State      string      `json:"state"`  // raised or cleared
+	Since      time.Time   `json:"since"`  // when the alarm was raised
+	Source     string      `json:"source"` // telegram or reading
+	Message    string      `json:"message,omitempty"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Annotation *Annotation `json:"annotation,omitempty"`
+}
+
+// Annotation is the locally assigned description of a meter.
+type Annotation struct {
+	Name     string            `json:"name,omitempty"`
+	Location *Location         `json:"location,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+type Location struct {
+	Building  string `json:"building,omitempty"`
+	Floor     string `json:"floor,omitempty"`
+	Apartment string `json:"apartment,omitempty"`
+}
+
+// Reading is a meter reading with normalized measurements, independent of the wmbusmeters driver.
+type Reading struct {
+	MeterId      string        `json:"meter_id"`
+	Driver       string        `json:"driver,omitempty"`
+	Media        string        `json:"media,omitempty"`
+	Timestamp    *time.Time    `json:"timestamp,omitempty"`  // gateway receive time
+	MeterTime    *time.Time    `json:"meter_time,omitempty"` // time reported by the meter's clock
+	ClockSkew    *float64      `json:"clock_skew,omitempty"` // estimated seconds the meter's clock is ahead
+	Measurements []Measurement `json:"measurements"`
+	Annotation   *Annotation   `json:"annotation,omitempty"`
+	Aggregate    *Aggregate    `json:"aggregate,omitempty"` // set if the reading combines several readings
+}
+
+// Aggregate describes the readings combined into one.
+type Aggregate struct {
+	Function string    `json:"function"`
+	Samples  int       `json:"samples"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+type Measurement struct {
+	Field         string     `json:"field"` // field name in the wmbusmeters reading
+	Name          string     `json:"name"`  // field name without unit, e.g. total or flow_temperature
+	Quantity      string     `json:"quantity"`
+	Value         float64    `json:"value"`
+	Unit          string     `json:"unit"`
+	Tariff        int        `json:"tariff,omitempty"`
+	StorageNumber int        `json:"storage_number,omitempty"`
+	Timestamp     *time.Time `json:"timestamp,omitempty"`
+	Flags         []string   `json:"flags,omitempty"` // plausibility flags, e.g. decrease or spike
+}
+
+// Derived holds the values computed from consecutive readings of a meter's counters.
+type Derived struct {
+	MeterId    string      `json:"meter_id"`
+	Timestamp  *time.Time  `json:"timestamp,omitempty"`
+	Intervals  []Interval  `json:"intervals,omitempty"`
+	Totals     []Total     `json:"totals,omitempty"`
+	Annotation *Annotation `json:"annotation,omitempty"`
+}
+
+// Interval is the consumption of a counter since its previous reading.
+type Interval struct {
+	Field       string    `json:"field"`
+	Quantity    string    `json:"quantity"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Consumption float64   `json:"consumption"`
+	Unit        string    `json:"unit"`
+	Rate        *float64  `json:"rate,omitempty"` // average flow or power, e.g. in m3/h or kW
+	RateUnit    string    `json:"rate_unit,omitempty"`
+}
+
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// Total is the consumption of a counter during a completed day or month.
+type Total struct {
+	Field       string    `json:"field"`
+	Quantity    string    `json:"quantity"`
+	Period      string    `json:"period"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Consumption float64   `json:"consumption"`
+	Unit        string    `json:"unit"`
 }
@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nimbusmgw
+
+import "time"
+
+// ObserveClock records the time reported by the meter's clock for a telegram received at receivedAt and returns
+// the estimated clock skew of the meter, positive if its clock is ahead. Unknown devices get the skew of this
+// telegram only.
+func (dm *DeviceManager) ObserveClock(id string, meterTime time.Time, receivedAt time.Time) time.Duration {
+	skew := meterTime.Sub(receivedAt)
+	dm.mux.Lock()
+	defer dm.mux.Unlock()
+	if _, ok := dm.devices[id]; !ok {
+		return skew
+	}
+	m := dm.metaOf(id)
+	if m.ClockSkew != nil {
+		// moving average, meters often report their clock in whole minutes only
+		skew = (*m.ClockSkew*7 + skew) / 8
+	}
+	m.ClockSkew = &skew
+	dm.persist(id)
+	return skew
+}
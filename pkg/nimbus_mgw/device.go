@@ -17,62 +17,145 @@
 package nimbusmgw
 
 import (
+	"context"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/mgw"
-	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
 )
 
 type Device struct {
-	Id           string
-	Name         string
-	DeviceTypeId string
+	Id           string    `json:"id"`
+	Name         string    `json:"name"`
+	DeviceTypeId string    `json:"device_type_id"`
+	State        mgw.State `json:"state,omitempty"` // online if empty
 }
 
 func (d Device) GetInfo() mgw.DeviceInfo {
+	state := d.State
+	if state == "" {
+		state = mgw.Online
+	}
 	return mgw.DeviceInfo{
 		Id:         d.Id,
 		Name:       d.Name,
 		DeviceType: d.DeviceTypeId,
-		State:      "online", // actually unknown, but not allowed
+		State:      state,
 	}
 }
 
 type DeviceManager struct {
-	devices   map[string]*Device
-	mux       sync.RWMutex
-	mgwClient *mgw.Client[Device]
+	devices map[string]*Device
+	meta    map[string]*meta
+	mux     sync.RWMutex
+	sync    *deviceSync
+	store   *checkpoint.Store
 }
 
-func NewDeviceManager(mgwClient *mgw.Client[Device]) *DeviceManager {
-	return &DeviceManager{
-		devices:   map[string]*Device{},
-		mux:       sync.RWMutex{},
-		mgwClient: mgwClient,
+// NewDeviceManager creates a DeviceManager holding the devices persisted in store. They are announced
+// to the mgw with the next Refresh. Changes are published to the mgw once StartSync was called.
+func NewDeviceManager(mgwClient *mgw.Client[Device], store *checkpoint.Store) *DeviceManager {
+	dm := &DeviceManager{
+		devices: map[string]*Device{},
+		meta:    map[string]*meta{},
+		mux:     sync.RWMutex{},
+		sync:    newDeviceSync(mgwClient),
+		store:   store,
 	}
+	dm.load()
+	return dm
+}
+
+// StartSync starts publishing device changes to the mgw.
+func (dm *DeviceManager) StartSync(ctx context.Context, wg *sync.WaitGroup, cfg config.DeviceSync) {
+	dm.sync.start(ctx, wg, cfg)
 }
 
-func (dm *DeviceManager) AddIdempotent(d *Device) error {
+func (dm *DeviceManager) AddIdempotent(d *Device) {
 	dm.mux.RLock()
 	_, ok := dm.devices[d.Id]
 	dm.mux.RUnlock()
 	if ok {
-		return nil
+		return
 	}
 	dm.mux.Lock()
 	dm.devices[d.Id] = d
+	dm.persist(d.Id)
 	dm.mux.Unlock()
-	return dm.mgwClient.SetDevice(*d)
+	dm.sync.set(*d)
 }
 
-func (dm *DeviceManager) Refresh() {
+func (dm *DeviceManager) Get(id string) (Device, bool) {
 	dm.mux.RLock()
 	defer dm.mux.RUnlock()
+	d, ok := dm.devices[id]
+	if !ok {
+		return Device{}, false
+	}
+	return *d, true
+}
+
+// Update replaces a device and sends it to the mgw if it changed.
+func (dm *DeviceManager) Update(d *Device) {
+	dm.mux.Lock()
+	old, ok := dm.devices[d.Id]
+	if ok && d.State == "" {
+		d.State = old.State
+	}
+	if ok && *old == *d {
+		dm.mux.Unlock()
+		return
+	}
+	dm.devices[d.Id] = d
+	dm.persist(d.Id)
+	dm.mux.Unlock()
+	dm.sync.set(*d)
+}
+
+// List returns all devices, sorted by id.
+func (dm *DeviceManager) List() []Device {
+	dm.mux.RLock()
+	devices := make([]Device, 0, len(dm.devices))
+	for _, d := range dm.devices {
+		devices = append(devices, *d)
+	}
+	dm.mux.RUnlock()
+	slices.SortFunc(devices, func(a, b Device) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return devices
+}
+
+// Remove deletes a device with its metadata and removes it from the mgw.
+func (dm *DeviceManager) Remove(id string) {
+	dm.mux.Lock()
+	_, ok := dm.devices[id]
+	dm.remove(id)
+	dm.mux.Unlock()
+	if ok {
+		dm.sync.remove(id)
+	}
+}
+
+// remove deletes a device with its metadata. dm.mux has to be held.
+func (dm *DeviceManager) remove(id string) {
+	delete(dm.devices, id)
+	delete(dm.meta, id)
+	dm.store.Delete(deviceBucket, id)
+}
+
+// Refresh queues all devices for publishing to the mgw.
+func (dm *DeviceManager) Refresh() {
+	dm.mux.RLock()
+	devices := make([]Device, 0, len(dm.devices))
 	for _, d := range dm.devices {
-		err := dm.mgwClient.SetDevice(*d)
-		if err != nil {
-			util.Logger.Error("unable to set device at mgw", "err", err)
-			continue
-		}
+		devices = append(devices, *d)
+	}
+	dm.mux.RUnlock()
+	for _, d := range devices {
+		dm.sync.set(d)
 	}
 }
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nimbusmgw
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// duplicateWindow is the gap below which a touch is taken as the same transmission and not used to learn the
+// transmission interval. wmbusmeters logs a telegram and its reading within a few milliseconds.
+const duplicateWindow = 5 * time.Second
+
+// Touch records a transmission of a device received from source and sets the device online again if it
+// was marked offline. Unknown devices are ignored.
+func (dm *DeviceManager) Touch(id string, source string) {
+	now := time.Now()
+	dm.mux.Lock()
+	d, ok := dm.devices[id]
+	if !ok {
+		dm.mux.Unlock()
+		return
+	}
+	m := dm.metaOf(id)
+	gap := now.Sub(m.LastSeen)
+	switch {
+	case m.LastSeen.IsZero():
+		m.LastSeen = now
+	case gap < duplicateWindow:
+		// the same transmission, seen again by another source, e.g. encrypted and decrypted
+	case m.Interval == 0:
+		m.Interval = gap
+		m.LastSeen = now
+	default:
+		// moving average, robust against single missed or repeated telegrams
+		m.Interval = (m.Interval*7 + gap) / 8
+		m.LastSeen = now
+	}
+	m.Source = source
+	if d.State != mgw.Offline {
+		dm.persist(id)
+		dm.mux.Unlock()
+		return
+	}
+	online := *d
+	online.State = mgw.Online
+	dm.devices[id] = &online
+	dm.persist(id)
+	dm.mux.Unlock()
+	util.Logger.Info("device transmits again, setting online", "device_id", id)
+	dm.sync.set(online)
+}
+
+// StartLivenessCheck periodically marks devices offline, or removes them, that did not transmit within
+// the silence period given by the current configuration.
+func (dm *DeviceManager) StartLivenessCheck(ctx context.Context, wg *sync.WaitGroup, reloader *config.Reloader) {
+	ticker := time.NewTicker(time.Minute)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				dm.checkLiveness(reloader.Get())
+			}
+		}
+	}()
+}
+
+func (dm *DeviceManager) checkLiveness(cfg *config.Config) {
+	lc := cfg.Pipeline.Liveness
+	if lc.OfflineAfter <= 0 {
+		return
+	}
+	now := time.Now()
+	silent := []Device{}
+	dm.mux.Lock()
+	for id, m := range dm.meta {
+		d, ok := dm.devices[id]
+		if !ok || d.State == mgw.Offline || m.LastSeen.IsZero() {
+			continue
+		}
+		if now.Sub(m.LastSeen) > silencePeriod(cfg, id, m) {
+			silent = append(silent, *d)
+		}
+	}
+	for _, d := range silent {
+		if lc.Remove {
+			dm.remove(d.Id)
+		} else {
+			offline := d
+			offline.State = mgw.Offline
+			dm.devices[d.Id] = &offline
+			dm.persist(d.Id)
+		}
+	}
+	dm.mux.Unlock()
+
+	for _, d := range silent {
+		if lc.Remove {
+			util.Logger.Warn("device stopped transmitting, removing", "device_id", d.Id)
+			dm.sync.remove(d.Id)
+		} else {
+			util.Logger.Warn("device stopped transmitting, setting offline", "device_id", d.Id)
+			d.State = mgw.Offline
+			dm.sync.set(d)
+		}
+	}
+}
+
+// silencePeriod returns how long a device may be silent. With a known transmission interval, configured
+// or learned, the device may miss MissedTransmissions telegrams, but never less than OfflineAfter.
+func silencePeriod(cfg *config.Config, id string, m *meta) time.Duration {
+	lc := cfg.Pipeline.Liveness
+	interval := m.Interval
+	if m, ok := cfg.Meter(id); ok && m.ExpectedInterval > 0 {
+		interval = time.Duration(m.ExpectedInterval)
+	}
+	period := time.Duration(lc.OfflineAfter)
+	if interval > 0 && lc.MissedTransmissions > 0 {
+		period = max(period, interval*time.Duration(lc.MissedTransmissions))
+	}
+	return period
+}
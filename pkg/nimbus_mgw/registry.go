@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nimbusmgw
+
+import (
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const deviceBucket = "devices"
+
+type meta struct {
+	FirstSeen time.Time      `json:"first_seen"`
+	LastSeen  time.Time      `json:"last_seen,omitempty"`
+	Interval  time.Duration  `json:"interval,omitempty"` // learned transmission interval, 0 until two telegrams were seen
+	Source    string         `json:"source,omitempty"`
+	ClockSkew *time.Duration `json:"clock_skew,omitempty"` // estimated offset of the meter's clock, nil until reported
+}
+
+// deviceRecord is the persisted form of a device.
+type deviceRecord struct {
+	Device
+	meta
+}
+
+// metaOf returns the metadata of a device, creating it on first use. dm.mux has to be held.
+func (dm *DeviceManager) metaOf(id string) *meta {
+	m, ok := dm.meta[id]
+	if !ok {
+		m = &meta{FirstSeen: time.Now()}
+		dm.meta[id] = m
+	}
+	return m
+}
+
+// persist stores a device with its metadata. dm.mux has to be held.
+func (dm *DeviceManager) persist(id string) {
+	d, ok := dm.devices[id]
+	if !ok {
+		return
+	}
+	err := dm.store.Set(deviceBucket, id, deviceRecord{Device: *d, meta: *dm.metaOf(id)})
+	if err != nil {
+		util.Logger.Error("unable to persist device", "device_id", id, "err", err)
+	}
+}
+
+func (dm *DeviceManager) load() {
+	dm.mux.Lock()
+	defer dm.mux.Unlock()
+	for _, id := range dm.store.Keys(deviceBucket) {
+		var r deviceRecord
+		ok, err := dm.store.Get(deviceBucket, id, &r)
+		if err != nil || !ok {
+			util.Logger.Error("unable to load persisted device", "device_id", id, "err", err)
+			continue
+		}
+		d := r.Device
+		m := r.meta
+		dm.devices[id] = &d
+		dm.meta[id] = &m
+	}
+	util.Logger.Info("loaded persisted devices", "count", len(dm.devices))
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nimbusmgw
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+type syncOp struct {
+	device    Device
+	delete    bool
+	attempt   int
+	notBefore time.Time
+}
+
+// deviceSync publishes device changes to the mgw in the background. Repeated changes of a device are
+// coalesced into one publish, publishing is rate limited and failed publishes are retried with backoff.
+type deviceSync struct {
+	client  *mgw.Client[Device]
+	pending map[string]*syncOp
+	order   []string
+	mux     sync.Mutex
+	wake    chan struct{}
+}
+
+func newDeviceSync(client *mgw.Client[Device]) *deviceSync {
+	return &deviceSync{
+		client:  client,
+		pending: map[string]*syncOp{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (s *deviceSync) set(d Device) {
+	s.enqueue(&syncOp{device: d})
+}
+
+func (s *deviceSync) remove(id string) {
+	s.enqueue(&syncOp{device: Device{Id: id}, delete: true})
+}
+
+func (s *deviceSync) enqueue(op *syncOp) {
+	s.mux.Lock()
+	if _, ok := s.pending[op.device.Id]; !ok {
+		s.order = append(s.order, op.device.Id)
+	}
+	s.pending[op.device.Id] = op
+	s.mux.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the first due operation, or returns how long to wait for one.
+func (s *deviceSync) next() (*syncOp, time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	now := time.Now()
+	wait := time.Duration(-1)
+	for i, id := range s.order {
+		op := s.pending[id]
+		if d := op.notBefore.Sub(now); d > 0 {
+			if wait < 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		s.order = append(s.order[:i:i], s.order[i+1:]...)
+		delete(s.pending, id)
+		return op, 0
+	}
+	return nil, wait
+}
+
+// retry re-queues a failed operation unless a newer one for the same device is pending.
+func (s *deviceSync) retry(op *syncOp, maxDelay time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.pending[op.device.Id]; ok {
+		return
+	}
+	op.attempt++
+	op.notBefore = time.Now().Add(min(time.Second<<min(op.attempt, 16), maxDelay))
+	s.pending[op.device.Id] = op
+	s.order = append(s.order, op.device.Id)
+}
+
+func (s *deviceSync) start(ctx context.Context, wg *sync.WaitGroup, cfg config.DeviceSync) {
+	limiter := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer limiter.Stop()
+		for {
+			op, wait := s.next()
+			if op == nil {
+				var timer <-chan time.Time
+				if wait >= 0 {
+					timer = time.After(wait)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-s.wake:
+				case <-timer:
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-limiter.C:
+			}
+			var err error
+			if op.delete {
+				err = s.client.DeleteDevice(op.device.Id)
+			} else {
+				err = s.client.SetDevice(op.device)
+			}
+			if err != nil {
+				util.Logger.Warn("unable to sync device with mgw, retrying", "device_id", op.device.Id, "delete", op.delete, "attempt", op.attempt+1, "err", err)
+				s.retry(op, time.Duration(cfg.MaxRetryDelay))
+			}
+		}
+	}()
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plausibility
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/measurement"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/units"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const bucket = "plausibility"
+
+// values below this share of the previous value are taken for a reset of the meter instead of a decrease
+const resetRatio = 0.1
+
+// counter is the last accepted value of a counter.
+type counter struct {
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+	Time      time.Time `json:"time"`
+	ChangedAt time.Time `json:"changed_at"`
+	// Candidate is the last withheld value, it becomes the new value after AcceptAfter consistent readings.
+	Candidate *counter `json:"candidate,omitempty"`
+	Confirmed int      `json:"confirmed,omitempty"`
+}
+
+// Checker compares cumulative counters with their previous values.
+type Checker struct {
+	counters *checkpoint.Bucket[counter]
+	mux      sync.Mutex
+}
+
+// Result lists the flags per field of a reading and the fields that were withheld.
+type Result struct {
+	Flags    map[string][]string
+	Withheld []string
+}
+
+// New creates a Checker that remembers previous values in store, see checkpoint.Bucket.
+func New(store *checkpoint.Store) *Checker {
+	return &Checker{counters: checkpoint.NewBucket[counter](store, bucket)}
+}
+
+// Check flags the current cumulative counters of r and removes measurements whose flags are withheld by cfg.
+// Withheld values do not replace the previous value, so following readings are compared to the last plausible one.
+// Resets always do, the meter counts from the new value on. So do withheld values once cfg.AcceptAfter readings
+// in a row were consistent with each other, e.g. after a meter exchange, the last of them is forwarded.
+func (c *Checker) Check(r *model.Reading, cfg config.Plausibility) Result {
+	res := Result{Flags: map[string][]string{}}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	kept := r.Measurements[:0]
+	for _, m := range r.Measurements {
+		if m.StorageNumber != 0 || !measurement.Cumulative(m.Quantity) {
+			kept = append(kept, m)
+			continue
+		}
+		t := time.Now()
+		if m.Timestamp != nil {
+			t = *m.Timestamp
+		}
+		key := r.MeterId + "/" + m.Field
+		prev, ok := c.counters.Get(key)
+		next := counter{Value: m.Value, Unit: m.Unit, Time: t, ChangedAt: t}
+		if ok {
+			if t.Before(prev.Time) {
+				// late or replayed value, the stored one is newer
+				kept = append(kept, m)
+				continue
+			}
+			m.Flags = check(prev, m, t, cfg)
+			if m.Value == prev.Value {
+				next.ChangedAt = prev.ChangedAt
+			}
+		}
+		if len(m.Flags) > 0 {
+			res.Flags[m.Field] = m.Flags
+			util.Logger.Warn("implausible meter value", "meter_id", r.MeterId, "field", m.Field, "value", m.Value, "previous", prev.Value, "flags", m.Flags)
+		}
+		withhold := slices.ContainsFunc(m.Flags, func(f string) bool { return slices.Contains(cfg.Withhold, f) })
+		if withhold && !slices.Contains(m.Flags, config.FlagReset) {
+			prev.Candidate, prev.Confirmed = confirm(prev, next, m, cfg)
+			if cfg.AcceptAfter == 0 || prev.Confirmed < cfg.AcceptAfter {
+				c.counters.Set(key, prev)
+			} else {
+				util.Logger.Warn("accepting implausible meter value as new baseline", "meter_id", r.MeterId, "field", m.Field, "value", m.Value, "readings", prev.Confirmed)
+				withhold = false
+			}
+		}
+		if !withhold || slices.Contains(m.Flags, config.FlagReset) {
+			c.counters.Set(key, next)
+		}
+		if withhold {
+			res.Withheld = append(res.Withheld, m.Field)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	r.Measurements = kept
+	return res
+}
+
+// confirm returns the withheld value as candidate of the counter and the number of consistent readings in a row.
+func confirm(prev counter, next counter, m model.Measurement, cfg config.Plausibility) (*counter, int) {
+	if prev.Candidate == nil {
+		return &next, 1
+	}
+	flags := check(*prev.Candidate, m, next.Time, cfg)
+	if slices.ContainsFunc(flags, func(f string) bool { return f != config.FlagFrozen }) {
+		return &next, 1
+	}
+	return &next, prev.Confirmed + 1
+}
+
+func check(prev counter, m model.Measurement, t time.Time, cfg config.Plausibility) []string {
+	var flags []string
+	value, err := units.Convert(prev.Value, prev.Unit, m.Unit)
+	if err != nil {
+		// the unit changed to one of another quantity, start over
+		return nil
+	}
+	switch {
+	case m.Value < value*resetRatio:
+		flags = append(flags, config.FlagReset)
+	case m.Value < value:
+		flags = append(flags, config.FlagDecrease)
+	case m.Value == value:
+		if cfg.FrozenAfter > 0 && t.Sub(prev.ChangedAt) >= time.Duration(cfg.FrozenAfter) {
+			flags = append(flags, config.FlagFrozen)
+		}
+	default:
+		rate, ok := cfg.MaxRates[m.Quantity]
+		hours := t.Sub(prev.Time).Hours()
+		if !ok || hours <= 0 {
+			break
+		}
+		delta, err := units.Convert(m.Value-value, m.Unit, rate.Unit)
+		if err != nil {
+			break
+		}
+		if delta/hours > rate.Value {
+			flags = append(flags, config.FlagSpike)
+		}
+	}
+	return flags
+}
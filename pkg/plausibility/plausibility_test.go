@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plausibility
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	sb_config_types "github.com/SENERGY-Platform/go-service-base/config-hdl/types"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+func TestCheck(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := config.Plausibility{
+		Enabled:     true,
+		MaxRates:    map[string]config.Rate{"volume": {Value: 1, Unit: "m3"}},
+		FrozenAfter: sb_config_types.Duration(2 * time.Hour),
+		Withhold:    []string{config.FlagDecrease, config.FlagSpike},
+		AcceptAfter: 3,
+	}
+	steps := []struct {
+		name      string
+		value     float64
+		wantFlags []string
+		withheld  bool
+	}{
+		{"first", 100, nil, false},
+		{"increase", 100.5, nil, false},
+		{"unchanged", 100.5, nil, false},
+		{"frozen", 100.5, []string{config.FlagFrozen}, false},
+		{"spike", 200, []string{config.FlagSpike}, true},
+		{"compared to last plausible", 100.6, nil, false},
+		{"decrease", 90, []string{config.FlagDecrease}, true},
+		{"decrease, consistent", 90.1, []string{config.FlagDecrease}, true},
+		{"decrease, accepted", 90.2, []string{config.FlagDecrease}, false},
+		{"new baseline", 90.3, nil, false},
+		{"reset", 1, []string{config.FlagReset}, false},
+		{"after reset", 1.1, nil, false},
+	}
+	c := New(nil)
+	for i, step := range steps {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		r := &model.Reading{MeterId: "12345678", Measurements: []model.Measurement{
+			{Field: "total_m3", Quantity: "volume", Value: step.value, Unit: "m3", Timestamp: &ts},
+		}}
+		res := c.Check(r, cfg)
+		if got := res.Flags["total_m3"]; !slices.Equal(got, step.wantFlags) {
+			t.Errorf("%s: flags = %v, want %v", step.name, got, step.wantFlags)
+		}
+		if withheld := slices.Contains(res.Withheld, "total_m3"); withheld != step.withheld {
+			t.Errorf("%s: withheld = %v, want %v", step.name, withheld, step.withheld)
+		}
+		if len(r.Measurements) == 0 == !step.withheld {
+			t.Errorf("%s: %d measurements left", step.name, len(r.Measurements))
+		}
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package schema validates wmbusmeters JSON readings against per driver schemas and coerces their values.
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+)
+
+//go:embed drivers/*.json
+var bundled embed.FS
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+// common fields of the readings of all drivers
+var common = map[string]config.SchemaField{
+	"id":        {Type: config.SchemaTypeString, Required: true},
+	"name":      {Type: config.SchemaTypeString, Required: true},
+	"media":     {Type: config.SchemaTypeString},
+	"meter":     {Type: config.SchemaTypeString},
+	"driver":    {Type: config.SchemaTypeString},
+	"timestamp": {Type: config.SchemaTypeString},
+	"rssi_dbm":  {Type: config.SchemaTypeNumber, Min: ptr(-150), Max: ptr(0)},
+}
+
+// Issue is a problem with a field of a reading.
+type Issue struct {
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+// Result describes the validation of a reading. Invalid fields were removed from the reading, Drift lists
+// deviations from the driver's schema that hint at changed wmbusmeters output.
+type Result struct {
+	Driver  string
+	Invalid []Issue
+	Drift   []Issue
+	Changed bool // the reading was modified by coercion or removal of fields
+}
+
+type Registry struct {
+	schemas map[string]config.Schema
+}
+
+// New creates a registry of the bundled schemas, extended by schemas.
+func New(schemas []config.Schema) (*Registry, error) {
+	r := &Registry{schemas: map[string]config.Schema{}}
+	entries, err := bundled.ReadDir("drivers")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		b, err := bundled.ReadFile("drivers/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		var s config.Schema
+		err = json.Unmarshal(b, &s)
+		if err != nil {
+			return nil, fmt.Errorf("bundled schema %s: %w", entry.Name(), err)
+		}
+		r.schemas[s.Driver] = s
+	}
+	for _, s := range schemas {
+		merged, ok := r.schemas[s.Driver]
+		if !ok {
+			r.schemas[s.Driver] = s
+			continue
+		}
+		merged.Fields = maps.Clone(merged.Fields)
+		maps.Copy(merged.Fields, s.Fields)
+		merged.Strict = s.Strict
+		r.schemas[s.Driver] = merged
+	}
+	return r, nil
+}
+
+// Validate checks and coerces the fields of reading j in place. Readings of drivers without schema are
+// only checked for the fields all readings share.
+func (r *Registry) Validate(j map[string]any) Result {
+	res := Result{Driver: stringOf(j["driver"])}
+	if res.Driver == "" {
+		res.Driver = stringOf(j["meter"])
+	}
+	s, known := r.schemas[res.Driver]
+
+	names := slices.Sorted(maps.Keys(common))
+	if known {
+		names = append(names, slices.Sorted(maps.Keys(s.Fields))...)
+	}
+	for _, name := range names {
+		f, ok := s.Fields[name]
+		fromDriver := ok
+		if !ok {
+			f = common[name]
+		}
+		issue, drift, changed := check(j, name, f)
+		if changed {
+			res.Changed = true
+		}
+		if issue != "" {
+			res.Invalid = append(res.Invalid, Issue{Field: name, Problem: issue})
+		}
+		if drift != "" && fromDriver {
+			res.Drift = append(res.Drift, Issue{Field: name, Problem: drift})
+		}
+	}
+	if known && s.Strict {
+		for _, name := range slices.Sorted(maps.Keys(j)) {
+			if _, ok := s.Fields[name]; !ok {
+				if _, ok := common[name]; !ok {
+					res.Drift = append(res.Drift, Issue{Field: name, Problem: "unknown field"})
+				}
+			}
+		}
+	}
+	return res
+}
+
+// check validates a single field, returning why it is invalid, how it deviates from the schema and whether it
+// was changed.
+func check(j map[string]any, name string, f config.SchemaField) (issue string, drift string, changed bool) {
+	v, ok := j[name]
+	if !ok || v == nil {
+		if f.Required {
+			return "missing", "missing", false
+		}
+		return "", "", false
+	}
+	coerced, ok := coerce(v, f.Type)
+	if !ok {
+		delete(j, name)
+		return fmt.Sprintf("%T is not a %s", v, f.Type), fmt.Sprintf("type %T instead of %s", v, f.Type), true
+	}
+	if fmt.Sprintf("%T", coerced) != fmt.Sprintf("%T", v) {
+		j[name] = coerced
+		drift = fmt.Sprintf("type %T instead of %s", v, f.Type)
+		changed = true
+	}
+	if n, ok := coerced.(float64); ok {
+		if (f.Min != nil && n < *f.Min) || (f.Max != nil && n > *f.Max) {
+			delete(j, name)
+			return fmt.Sprintf("%v out of range", n), drift, true
+		}
+	}
+	return "", drift, changed
+}
+
+func coerce(v any, typ string) (any, bool) {
+	switch typ {
+	case config.SchemaTypeNumber:
+		switch v := v.(type) {
+		case float64:
+			return v, true
+		case string:
+			n, err := strconv.ParseFloat(v, 64)
+			return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+		}
+	case config.SchemaTypeString:
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case config.SchemaTypeBoolean:
+		switch v := v.(type) {
+		case bool:
+			return v, true
+		case string:
+			b, err := strconv.ParseBool(v)
+			return b, err == nil
+		}
+	}
+	return nil, false
+}
+
+func stringOf(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+)
+
+func TestValidate(t *testing.T) {
+	r, err := New([]config.Schema{
+		{Driver: "test", Strict: true, Fields: map[string]config.SchemaField{
+			"total_m3":       {Type: config.SchemaTypeNumber, Required: true, Min: ptr(0)},
+			"current_status": {Type: config.SchemaTypeString},
+			"leaking":        {Type: config.SchemaTypeBoolean},
+		}},
+		{Driver: "multical21", Fields: map[string]config.SchemaField{
+			"volume_flow_m3h": {Type: config.SchemaTypeNumber},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		j       map[string]any
+		want    map[string]any // reading after validation
+		invalid []Issue
+		drift   []Issue
+		changed bool
+	}{
+		{
+			name: "valid",
+			j:    map[string]any{"id": "12345678", "name": "m", "driver": "test", "total_m3": 1.5, "current_status": "OK"},
+			want: map[string]any{"id": "12345678", "name": "m", "driver": "test", "total_m3": 1.5, "current_status": "OK"},
+		},
+		{
+			name:    "coerced",
+			j:       map[string]any{"id": "12345678", "name": "m", "driver": "test", "total_m3": "1.5", "leaking": "true"},
+			want:    map[string]any{"id": "12345678", "name": "m", "driver": "test", "total_m3": 1.5, "leaking": true},
+			drift:   []Issue{{"leaking", "type string instead of boolean"}, {"total_m3", "type string instead of number"}},
+			changed: true,
+		},
+		{
+			name:    "out of range and wrong type",
+			j:       map[string]any{"id": "12345678", "name": "m", "driver": "test", "total_m3": -1.0, "rssi_dbm": 10.0, "leaking": 1.0},
+			want:    map[string]any{"id": "12345678", "name": "m", "driver": "test"},
+			invalid: []Issue{{"rssi_dbm", "10 out of range"}, {"leaking", "float64 is not a boolean"}, {"total_m3", "-1 out of range"}},
+			drift:   []Issue{{"leaking", "type float64 instead of boolean"}},
+			changed: true,
+		},
+		{
+			name:    "missing and unknown fields",
+			j:       map[string]any{"id": "12345678", "name": "m", "meter": "test", "unknown": 1.0},
+			want:    map[string]any{"id": "12345678", "name": "m", "meter": "test", "unknown": 1.0},
+			invalid: []Issue{{"total_m3", "missing"}},
+			drift:   []Issue{{"total_m3", "missing"}, {"unknown", "unknown field"}},
+		},
+		{
+			name:    "bundled schema, extended",
+			j:       map[string]any{"id": "12345678", "name": "m", "driver": "multical21", "total_m3": 1.0, "volume_flow_m3h": "0.5"},
+			want:    map[string]any{"id": "12345678", "name": "m", "driver": "multical21", "total_m3": 1.0, "volume_flow_m3h": 0.5},
+			drift:   []Issue{{"volume_flow_m3h", "type string instead of number"}},
+			changed: true,
+		},
+		{
+			name:    "unknown driver, common fields only",
+			j:       map[string]any{"id": 12345678.0, "name": "m", "driver": "other", "total_m3": "x"},
+			want:    map[string]any{"id": "12345678", "name": "m", "driver": "other", "total_m3": "x"},
+			changed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := r.Validate(tt.j)
+			if !reflect.DeepEqual(tt.j, tt.want) {
+				t.Errorf("reading %v, want %v", tt.j, tt.want)
+			}
+			if !reflect.DeepEqual(res.Invalid, tt.invalid) {
+				t.Errorf("invalid %v, want %v", res.Invalid, tt.invalid)
+			}
+			if !reflect.DeepEqual(res.Drift, tt.drift) {
+				t.Errorf("drift %v, want %v", res.Drift, tt.drift)
+			}
+			if res.Changed != tt.changed {
+				t.Errorf("changed %v, want %v", res.Changed, tt.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+)
+
+// Sink receives the events of all devices. *mgw.Client satisfies this interface.
+type Sink interface {
+	SendEvent(deviceId string, serviceId string, msg []byte) error
+}
+
+func MarshalAndSendEvent(s Sink, deviceId string, serviceId string, value any) error {
+	msg, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.SendEvent(deviceId, serviceId, msg)
+}
+
+// New builds the sink described by cfgs. mgwSink is used for sinks of type config.SinkTypeMgw.
+func New(cfgs []config.Sink, mgwSink Sink, stdout io.Writer) (Multi, error) {
+	sinks := Multi{}
+	for i, cfg := range cfgs {
+		var s Sink
+		switch cfg.Type {
+		case config.SinkTypeMgw:
+			if mgwSink == nil {
+				return nil, fmt.Errorf("sinks[%d]: mgw sink not available", i)
+			}
+			s = mgwSink
+		case config.SinkTypeStdout:
+			s = NewWriter(stdout)
+		default:
+			return nil, fmt.Errorf("sinks[%d]: unknown type %q", i, cfg.Type)
+		}
+		if len(cfg.Services) > 0 {
+			s = &serviceFilter{services: cfg.Services, sink: s}
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// Multi forwards events to all contained sinks.
+type Multi []Sink
+
+func (m Multi) SendEvent(deviceId string, serviceId string, msg []byte) error {
+	errs := []error{}
+	for _, s := range m {
+		err := s.SendEvent(deviceId, serviceId, msg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type serviceFilter struct {
+	services []string
+	sink     Sink
+}
+
+func (f *serviceFilter) SendEvent(deviceId string, serviceId string, msg []byte) error {
+	if !slices.Contains(f.services, serviceId) {
+		return nil
+	}
+	return f.sink.SendEvent(deviceId, serviceId, msg)
+}
+
+// Writer writes every event as one JSON line.
+type Writer struct {
+	w   io.Writer
+	mux sync.Mutex
+}
+
+type writerEvent struct {
+	DeviceId  string          `json:"device_id"`
+	ServiceId string          `json:"service_id"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) SendEvent(deviceId string, serviceId string, msg []byte) error {
+	value := json.RawMessage(msg)
+	if !json.Valid(msg) {
+		value, _ = json.Marshal(string(msg))
+	}
+	line, err := json.Marshal(writerEvent{DeviceId: deviceId, ServiceId: serviceId, Value: value})
+	if err != nil {
+		return err
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	_, err = w.w.Write(append(line, '\n'))
+	return err
+}
+
+// Dynamic forwards to a sink that can be replaced at runtime.
+type Dynamic struct {
+	current atomic.Pointer[Multi]
+}
+
+func NewDynamic(s Multi) *Dynamic {
+	d := &Dynamic{}
+	d.Set(s)
+	return d
+}
+
+func (d *Dynamic) Set(s Multi) {
+	d.current.Store(&s)
+}
+
+func (d *Dynamic) SendEvent(deviceId string, serviceId string, msg []byte) error {
+	return d.current.Load().SendEvent(deviceId, serviceId, msg)
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telegram
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// Record is a data record of the application layer.
+type Record struct {
+	DIF      string `json:"dif"` // hex of the data information field and its extensions
+	VIF      string `json:"vif"` // hex of the value information field and its extensions
+	Function string `json:"function"`
+	Storage  int    `json:"storage"`
+	Tariff   int    `json:"tariff"`
+	Subunit  int    `json:"subunit"`
+	Quantity string `json:"quantity"`
+	Value    any    `json:"value"` // number, or string for dates, text and undecodable data
+	Unit     string `json:"unit,omitempty"`
+	Data     string `json:"data"` // hex of the raw data
+}
+
+var functions = []string{"instantaneous", "maximum", "minimum", "error"}
+
+// data length by the data field of the DIF, -1 for variable length
+var dataLengths = []int{0, 1, 2, 3, 4, 4, 6, 8, 0, 1, 2, 3, 4, -1, 6, 0}
+
+func decodeRecords(b []byte) ([]Record, error) {
+	records := []Record{}
+	i := 0
+	for i < len(b) {
+		start := i
+		dif := b[i]
+		i++
+		if dif == 0x2F {
+			// idle filler
+			continue
+		}
+		if dif == 0x0F || dif == 0x1F {
+			records = append(records, Record{
+				DIF:      hex.EncodeToString(b[start:i]),
+				Quantity: "manufacturer specific",
+				Value:    hex.EncodeToString(b[i:]),
+				Data:     hex.EncodeToString(b[i:]),
+			})
+			return records, nil
+		}
+		r := Record{
+			Function: functions[dif>>4&0x03],
+			Storage:  int(dif >> 6 & 0x01),
+		}
+		for n := 0; b[i-1]&0x80 != 0; n++ {
+			if i >= len(b) {
+				return records, fmt.Errorf("record at byte %d: truncated dif", start)
+			}
+			dife := b[i]
+			i++
+			r.Storage |= int(dife&0x0F) << (1 + 4*n)
+			r.Tariff |= int(dife>>4&0x03) << (2 * n)
+			r.Subunit |= int(dife>>6&0x01) << n
+		}
+		r.DIF = hex.EncodeToString(b[start:i])
+
+		vifStart := i
+		if i >= len(b) {
+			return records, fmt.Errorf("record at byte %d: missing vif", start)
+		}
+		v := b[i]
+		i++
+		ext := v&0x80 != 0
+		var info vif
+		switch v & 0x7F {
+		case 0x7B, 0x7D:
+			if i >= len(b) {
+				return records, fmt.Errorf("record at byte %d: truncated vif", start)
+			}
+			if v&0x7F == 0x7B {
+				info = extendedVifFB(b[i] & 0x7F)
+			} else {
+				info = extendedVifFD(b[i] & 0x7F)
+			}
+			ext = b[i]&0x80 != 0
+			i++
+		case 0x7C:
+			info = vif{quantity: "plain text unit", kind: kindNumber}
+		default:
+			info = primaryVif(v & 0x7F)
+		}
+		// combinable extensions are kept in the vif hex but not interpreted
+		for ext {
+			if i >= len(b) {
+				return records, fmt.Errorf("record at byte %d: truncated vife", start)
+			}
+			ext = b[i]&0x80 != 0
+			i++
+		}
+		if v&0x7F == 0x7C {
+			if i >= len(b) || i+1+int(b[i]) > len(b) {
+				return records, fmt.Errorf("record at byte %d: truncated plain text vif", start)
+			}
+			l := int(b[i])
+			info.unit = reversedString(b[i+1 : i+1+l])
+			i += 1 + l
+		}
+		r.VIF = hex.EncodeToString(b[vifStart:i])
+		r.Quantity, r.Unit = info.quantity, info.unit
+
+		l := dataLengths[dif&0x0F]
+		var lvar byte
+		if l == -1 {
+			if i >= len(b) {
+				return records, fmt.Errorf("record at byte %d: missing lvar", start)
+			}
+			lvar = b[i]
+			l = variableLength(lvar)
+			i++
+		}
+		if i+l > len(b) {
+			return records, fmt.Errorf("record at byte %d: %d data bytes expected, %d left", start, l, len(b)-i)
+		}
+		data := b[i : i+l]
+		i += l
+		r.Data = hex.EncodeToString(data)
+		r.Value = valueOf(dif&0x0F, info, data, lvar)
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func variableLength(lvar byte) int {
+	switch {
+	case lvar <= 0xBF:
+		return int(lvar)
+	case lvar <= 0xDF:
+		return int(lvar & 0x0F)
+	case lvar <= 0xEF:
+		return int(lvar - 0xE0)
+	case lvar <= 0xFA:
+		return 4 * int(lvar-0xEC)
+	default:
+		return 0
+	}
+}
+
+// valueOf interprets data according to the data field of the DIF and the VIF.
+func valueOf(coding byte, info vif, data []byte, lvar byte) any {
+	switch {
+	case info.kind == kindDate && len(data) == 2:
+		return dateG(data)
+	case info.kind == kindDateTime && len(data) == 4:
+		return dateTimeF(data)
+	case info.kind == kindDateTime && len(data) == 6:
+		return dateTimeI(data)
+	case coding == 0x0D && lvar <= 0xBF:
+		return reversedString(data)
+	case info.kind != kindNumber:
+		return hex.EncodeToString(data)
+	}
+	var v float64
+	switch coding {
+	case 0x01, 0x02, 0x03, 0x04, 0x06, 0x07:
+		v = float64(signedLE(data))
+	case 0x05:
+		v = float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))
+	case 0x09, 0x0A, 0x0B, 0x0C, 0x0E:
+		n, ok := bcd(data)
+		if !ok {
+			return hex.EncodeToString(data)
+		}
+		v = float64(n)
+	default:
+		return hex.EncodeToString(data)
+	}
+	if info.exp < 0 {
+		return v / math.Pow10(-info.exp)
+	}
+	return v * math.Pow10(info.exp)
+}
+
+func signedLE(b []byte) int64 {
+	var u uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	shift := 64 - 8*len(b)
+	return int64(u<<shift) >> shift
+}
+
+// bcd decodes little endian packed BCD. A high nibble F in the last byte marks a negative number.
+func bcd(b []byte) (int64, bool) {
+	var n int64
+	negative := false
+	for i := len(b) - 1; i >= 0; i-- {
+		hi, lo := b[i]>>4, b[i]&0x0F
+		if i == len(b)-1 && hi == 0x0F {
+			negative = true
+			hi = 0
+		}
+		if hi > 9 || lo > 9 {
+			return 0, false
+		}
+		n = n*100 + int64(hi)*10 + int64(lo)
+	}
+	if negative {
+		n = -n
+	}
+	return n, true
+}
+
+func reversedString(b []byte) string {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return string(r)
+}
+
+func dateG(b []byte) string {
+	day := b[0] & 0x1F
+	month := b[1] & 0x0F
+	year := int(b[0]>>5) | int(b[1]>>4)<<3
+	return fmt.Sprintf("%04d-%02d-%02d", 2000+year, month, day)
+}
+
+func dateTimeF(b []byte) string {
+	minute := b[0] & 0x3F
+	hour := b[1] & 0x1F
+	return fmt.Sprintf("%s %02d:%02d", dateG(b[2:4]), hour, minute)
+}
+
+func dateTimeI(b []byte) string {
+	second := b[0] & 0x3F
+	minute := b[1] & 0x3F
+	hour := b[2] & 0x1F
+	return fmt.Sprintf("%s %02d:%02d:%02d", dateG(b[3:5]), hour, minute, second)
+}
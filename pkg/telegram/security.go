@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telegram
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+)
+
+// Security describes how the application data is protected, as given by the configuration field of the transport layer.
+type Security struct {
+	Mode            int    `json:"mode"`
+	Name            string `json:"name"`
+	EncryptedBlocks int    `json:"encrypted_blocks,omitempty"`
+	Decrypted       bool   `json:"decrypted"`
+}
+
+var securityModes = map[int]string{
+	0:  "none",
+	5:  "AES-128-CBC, static key",
+	7:  "AES-128-CBC, ephemeral key",
+	8:  "AES-128-CTR",
+	10: "AES-128-CCM",
+	13: "TLS",
+}
+
+var ErrKeyRequired = errors.New("payload is encrypted, key required")
+
+func securityOf(config uint16) *Security {
+	mode := int(config >> 8 & 0x1F)
+	name, ok := securityModes[mode]
+	if !ok {
+		name = "reserved"
+	}
+	s := &Security{Mode: mode, Name: name}
+	if mode == 5 {
+		s.EncryptedBlocks = int(config >> 4 & 0x0F)
+	}
+	return s
+}
+
+// decrypt returns the plain payload. address is manufacturer, id, version and device type as sent in the link layer.
+func decrypt(s *Security, address []byte, acc byte, payload []byte, key []byte) ([]byte, error) {
+	switch s.Mode {
+	case 0:
+		return payload, nil
+	case 5:
+	default:
+		return payload, fmt.Errorf("security mode %d (%s) is not supported", s.Mode, s.Name)
+	}
+	if len(key) == 0 {
+		return payload, ErrKeyRequired
+	}
+	n := s.EncryptedBlocks * aes.BlockSize
+	if n == 0 || len(payload) < n {
+		return payload, fmt.Errorf("payload has %d bytes, %d encrypted bytes expected", len(payload), n)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return payload, err
+	}
+	iv := append(append([]byte{}, address...), acc, acc, acc, acc, acc, acc, acc, acc)
+	plain := make([]byte, len(payload))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain[:n], payload[:n])
+	copy(plain[n:], payload[n:])
+	if plain[0] != 0x2F || plain[1] != 0x2F {
+		return payload, errors.New("decryption failed, wrong key")
+	}
+	s.Decrypted = true
+	return plain, nil
+}
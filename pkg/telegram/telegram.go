@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package telegram decodes wireless M-Bus telegrams (EN 13757-4 link layer, EN 13757-3 application layer) as
+// logged by wmbusmeters, i.e. without CRCs.
+package telegram
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Telegram struct {
+	Link      LinkLayer       `json:"link_layer"`
+	ELL       *ExtendedLink   `json:"extended_link_layer,omitempty"`
+	Transport *TransportLayer `json:"transport_layer,omitempty"`
+	Security  *Security       `json:"security,omitempty"`
+	Records   []Record        `json:"records,omitempty"`
+	Payload   string          `json:"payload,omitempty"` // hex of the application data that was not decoded
+	Error     string          `json:"error,omitempty"`   // why decoding stopped early
+}
+
+type LinkLayer struct {
+	Length       int    `json:"length"`
+	C            byte   `json:"c"`
+	Function     string `json:"function"`
+	Manufacturer string `json:"manufacturer"`
+	Id           string `json:"id"`
+	Version      byte   `json:"version"`
+	DeviceType   byte   `json:"device_type"`
+	Media        string `json:"media"`
+	CI           byte   `json:"ci"`
+}
+
+// ExtendedLink is the extended link layer of CI 0x8C and 0x8D.
+type ExtendedLink struct {
+	CC         byte   `json:"cc"`
+	ACC        byte   `json:"acc"`
+	SessionNo  uint32 `json:"session_number,omitempty"`
+	PayloadCRC uint16 `json:"payload_crc,omitempty"`
+	Encrypted  bool   `json:"encrypted"`
+	NextCI     byte   `json:"next_ci"`
+}
+
+// TransportLayer is the header of the application layer. Address fields are only set for long headers.
+type TransportLayer struct {
+	Header       string `json:"header"` // none, short or long
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Id           string `json:"id,omitempty"`
+	Version      byte   `json:"version,omitempty"`
+	DeviceType   byte   `json:"device_type,omitempty"`
+	Media        string `json:"media,omitempty"`
+	ACC          byte   `json:"acc"`
+	Status       byte   `json:"status"`
+	Config       uint16 `json:"config"`
+	address      []byte // manufacturer, id, version and device type as in the link layer
+}
+
+var ErrTooShort = errors.New("telegram too short")
+
+// ParseHex decodes a hex telegram. wmbusmeters separates header and payload with an underscore, which is ignored.
+func ParseHex(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	s = strings.NewReplacer("_", "", " ", "", "|", "").Replace(s)
+	return hex.DecodeString(s)
+}
+
+// MeterId returns the id of the meter that sent the telegram, as given by its link layer address.
+func MeterId(b []byte) (string, error) {
+	if len(b) < 8 {
+		return "", ErrTooShort
+	}
+	return idOf(b[4:8]), nil
+}
+
+// MeterIds returns the link layer id and, if it differs, the id of a long transport layer header. Repeaters and
+// CI 0x72 telegrams carry the address of the sending device in the link layer and that of the meter in the
+// transport layer, wmbusmeters reports either depending on the driver.
+func MeterIds(b []byte) ([]string, error) {
+	id, err := MeterId(b)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{id}
+	if t, err := Decode(b, nil); err == nil && t.Transport != nil && t.Transport.Id != "" && t.Transport.Id != id {
+		ids = append(ids, t.Transport.Id)
+	}
+	return ids, nil
+}
+
+// MeterId returns the id of the meter, which is the transport layer id for long headers, else the link layer id.
+func (t *Telegram) MeterId() string {
+	if t.Transport != nil && t.Transport.Id != "" {
+		return t.Transport.Id
+	}
+	return t.Link.Id
+}
+
+// Decode decodes a telegram, decrypting it with key if it is encrypted. key may be nil. An error is only returned
+// if the link layer is unreadable, later problems are reported in Telegram.Error along with the raw payload.
+func Decode(b []byte, key []byte) (*Telegram, error) {
+	if len(b) < 11 {
+		return nil, ErrTooShort
+	}
+	t := &Telegram{}
+	t.Link = LinkLayer{
+		Length:       int(b[0]),
+		C:            b[1],
+		Function:     functionOf(b[1]),
+		Manufacturer: manufacturerOf(b[2:4]),
+		Id:           idOf(b[4:8]),
+		Version:      b[8],
+		DeviceType:   b[9],
+		Media:        Media(b[9]),
+		CI:           b[10],
+	}
+	address := b[2:10]
+	rest := b[11:]
+	ci := b[10]
+
+	if ci == 0x8C || ci == 0x8D {
+		ell, r, err := decodeELL(ci, rest)
+		if err != nil {
+			t.fail(err, rest)
+			return t, nil
+		}
+		t.ELL = ell
+		if ell.Encrypted {
+			t.fail(errors.New("extended link layer encryption (AES-CTR) is not supported"), r)
+			return t, nil
+		}
+		ci = ell.NextCI
+		rest = r
+	}
+
+	tpl, r, err := decodeTPL(ci, rest)
+	if err != nil {
+		t.fail(err, rest)
+		return t, nil
+	}
+	t.Transport = tpl
+	rest = r
+	if tpl.address != nil {
+		address = tpl.address
+	}
+
+	if tpl.Header != "none" {
+		t.Security = securityOf(tpl.Config)
+		rest, err = decrypt(t.Security, address, tpl.ACC, rest, key)
+		if err != nil {
+			t.fail(err, rest)
+			return t, nil
+		}
+	}
+
+	t.Records, err = decodeRecords(rest)
+	if err != nil {
+		t.Error = err.Error()
+	}
+	return t, nil
+}
+
+func (t *Telegram) fail(err error, rest []byte) {
+	t.Error = err.Error()
+	t.Payload = hex.EncodeToString(rest)
+}
+
+func decodeELL(ci byte, b []byte) (*ExtendedLink, []byte, error) {
+	ell := &ExtendedLink{}
+	switch ci {
+	case 0x8C:
+		if len(b) < 3 {
+			return nil, b, ErrTooShort
+		}
+		ell.CC, ell.ACC, ell.NextCI = b[0], b[1], b[2]
+		return ell, b[3:], nil
+	default:
+		if len(b) < 9 {
+			return nil, b, ErrTooShort
+		}
+		ell.CC, ell.ACC = b[0], b[1]
+		ell.SessionNo = uint32(b[2]) | uint32(b[3])<<8 | uint32(b[4])<<16 | uint32(b[5])<<24
+		ell.Encrypted = ell.SessionNo>>29 != 0
+		ell.PayloadCRC = uint16(b[6]) | uint16(b[7])<<8
+		ell.NextCI = b[8]
+		return ell, b[9:], nil
+	}
+}
+
+func decodeTPL(ci byte, b []byte) (*TransportLayer, []byte, error) {
+	tpl := &TransportLayer{}
+	switch ci {
+	case 0x78:
+		tpl.Header = "none"
+		return tpl, b, nil
+	case 0x7A:
+		if len(b) < 4 {
+			return nil, b, ErrTooShort
+		}
+		tpl.Header = "short"
+		tpl.ACC, tpl.Status, tpl.Config = b[0], b[1], uint16(b[2])|uint16(b[3])<<8
+		return tpl, b[4:], nil
+	case 0x72:
+		if len(b) < 12 {
+			return nil, b, ErrTooShort
+		}
+		tpl.Header = "long"
+		tpl.Id = idOf(b[0:4])
+		tpl.Manufacturer = manufacturerOf(b[4:6])
+		tpl.Version, tpl.DeviceType, tpl.Media = b[6], b[7], Media(b[7])
+		tpl.ACC, tpl.Status, tpl.Config = b[8], b[9], uint16(b[10])|uint16(b[11])<<8
+		tpl.address = append(append([]byte{}, b[4:6]...), b[0], b[1], b[2], b[3], b[6], b[7])
+		return tpl, b[12:], nil
+	default:
+		return nil, b, fmt.Errorf("unsupported ci field 0x%02X", ci)
+	}
+}
+
+// manufacturerOf decodes the three letter flag of a manufacturer, e.g. KAM.
+func manufacturerOf(b []byte) string {
+	m := uint16(b[0]) | uint16(b[1])<<8
+	return string([]byte{byte(m>>10&0x1F) + 64, byte(m>>5&0x1F) + 64, byte(m&0x1F) + 64})
+}
+
+// idOf returns the meter id as printed on the meter, the bytes are sent in reverse order.
+func idOf(b []byte) string {
+	return fmt.Sprintf("%02x%02x%02x%02x", b[3], b[2], b[1], b[0])
+}
+
+func functionOf(c byte) string {
+	switch c {
+	case 0x44:
+		return "SND_NR"
+	case 0x46:
+		return "SND_IR"
+	case 0x47:
+		return "ACC_NR"
+	case 0x48:
+		return "ACC_DMD"
+	case 0x40:
+		return "SND_NKE"
+	case 0x53, 0x73:
+		return "SND_UD"
+	case 0x08, 0x18, 0x28, 0x38:
+		return "RSP_UD"
+	default:
+		return fmt.Sprintf("0x%02X", c)
+	}
+}
+
+var media = map[byte]string{
+	0x00: "other",
+	0x01: "oil",
+	0x02: "electricity",
+	0x03: "gas",
+	0x04: "heat (outlet)",
+	0x05: "steam",
+	0x06: "warm water",
+	0x07: "water",
+	0x08: "heat cost allocator",
+	0x09: "compressed air",
+	0x0A: "cooling (outlet)",
+	0x0B: "cooling (inlet)",
+	0x0C: "heat (inlet)",
+	0x0D: "heat/cooling",
+	0x0E: "bus/system component",
+	0x0F: "unknown",
+	0x15: "hot water",
+	0x16: "cold water",
+	0x17: "dual water",
+	0x18: "pressure",
+	0x19: "a/d converter",
+	0x1A: "smoke detector",
+	0x1B: "room sensor",
+	0x1C: "gas detector",
+	0x20: "breaker",
+	0x21: "valve",
+	0x25: "customer unit",
+	0x28: "waste water",
+	0x29: "garbage",
+	0x31: "communication controller",
+	0x32: "unidirectional repeater",
+	0x33: "bidirectional repeater",
+	0x36: "radio converter (system side)",
+	0x37: "radio converter (meter side)",
+}
+
+// Media returns the name of the medium of a device type, e.g. cold water for 0x16.
+func Media(t byte) string {
+	if m, ok := media[t]; ok {
+		return m
+	}
+	return fmt.Sprintf("0x%02X", t)
+}
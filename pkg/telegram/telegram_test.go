@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telegram
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// omsExample is the mode 5 example telegram of OMS specification volume 2, annex N, also used by wmbusmeters.
+const (
+	omsExample    = "2E4493157856341233037A2A0020255923C95AAA26D1B2E7493B013EC4A6F6D3529B520EDFF0EA6DEFC99D6D69EBF3"
+	omsExampleKey = "0102030405060708090A0B0C0D0E0F11"
+)
+
+type wantRecord struct {
+	quantity string
+	value    any
+	unit     string
+	storage  int
+	tariff   int
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name         string
+		telegram     string
+		key          string
+		manufacturer string
+		id           string
+		media        string
+		header       string
+		err          string // part of Telegram.Error, none expected if empty
+		records      []wantRecord
+	}{
+		{
+			name:     "mode 5, oms example",
+			telegram: omsExample, key: omsExampleKey,
+			manufacturer: "ELS", id: "12345678", media: "gas", header: "short",
+			records: []wantRecord{
+				{quantity: "volume", value: 28504.27, unit: "m3"},
+				{quantity: "date time", value: "2008-05-31 23:50"},
+				{quantity: "error flags", value: 0.0},
+			},
+		},
+		{
+			name:         "mode 5 without key",
+			telegram:     omsExample,
+			manufacturer: "ELS", id: "12345678", media: "gas", header: "short",
+			err: ErrKeyRequired.Error(),
+		},
+		{
+			name:     "mode 5 with wrong key",
+			telegram: omsExample, key: "000102030405060708090A0B0C0D0E0F",
+			manufacturer: "ELS", id: "12345678", media: "gas", header: "short",
+			err: "wrong key",
+		},
+		{
+			name:         "extended link layer, aes-ctr",
+			telegram:     "2E442D2C785634121B168D2091D37CAC21E1D68CDAFFCD3DC452BD802913FF7B1706CA9E355D6C2701CC24",
+			manufacturer: "KAM", id: "12345678", media: "cold water",
+			err: "not supported",
+		},
+		{
+			name: "unencrypted, no header",
+			telegram: "2C442D2C785634121B1678" +
+				"0C1378563412" + // volume, 8 digit BCD
+				"4C1301000000" + // volume, storage 1
+				"8C101302000000" + // volume, tariff 1
+				"0B137856F4" + // volume, negative 6 digit BCD
+				"0A5A3412" + // flow temperature, 4 digit BCD
+				"0DFD11056F6C6C6568" + // customer, LVAR text
+				"02FD170000" + // error flags
+				"2F2F", // idle filler
+			manufacturer: "KAM", id: "12345678", media: "cold water", header: "none",
+			records: []wantRecord{
+				{quantity: "volume", value: 12345.678, unit: "m3"},
+				{quantity: "volume", value: 0.001, unit: "m3", storage: 1},
+				{quantity: "volume", value: 0.002, unit: "m3", tariff: 1},
+				{quantity: "volume", value: -45.678, unit: "m3"},
+				{quantity: "flow temperature", value: 123.4, unit: "°C"},
+				{quantity: "customer", value: "hello"},
+				{quantity: "error flags", value: 0.0},
+			},
+		},
+		{
+			name:         "invalid bcd",
+			telegram:     "12442D2C785634121B16780C13785634A2",
+			manufacturer: "KAM", id: "12345678", media: "cold water", header: "none",
+			records: []wantRecord{{quantity: "volume", value: "785634a2", unit: "m3"}},
+		},
+		{
+			name:         "truncated record",
+			telegram:     "10442D2C785634121B16780C137856",
+			manufacturer: "KAM", id: "12345678", media: "cold water", header: "none",
+			err: "4 data bytes expected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ParseHex(tt.telegram)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var key []byte
+			if tt.key != "" {
+				key, _ = hex.DecodeString(tt.key)
+			}
+			got, err := Decode(b, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Link.Manufacturer != tt.manufacturer || got.Link.Id != tt.id || got.Link.Media != tt.media {
+				t.Errorf("link layer %+v, want %s %s %s", got.Link, tt.manufacturer, tt.id, tt.media)
+			}
+			if tt.header != "" && (got.Transport == nil || got.Transport.Header != tt.header) {
+				t.Errorf("transport layer %+v, want %s header", got.Transport, tt.header)
+			}
+			if tt.err == "" && got.Error != "" || !strings.Contains(got.Error, tt.err) {
+				t.Errorf("error %q, want %q", got.Error, tt.err)
+			}
+			assertRecords(t, got.Records, tt.records)
+		})
+	}
+}
+
+func assertRecords(t *testing.T, got []Record, want []wantRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%d records %+v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		r := got[i]
+		if r.Quantity != w.quantity || r.Value != w.value || r.Unit != w.unit || r.Storage != w.storage || r.Tariff != w.tariff {
+			t.Errorf("record %d = %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+// TestDecodeMode5LongHeader encrypts a payload addressed by a long header, whose address replaces that of the
+// link layer in the initialization vector.
+func TestDecodeMode5LongHeader(t *testing.T) {
+	key, _ := hex.DecodeString(omsExampleKey)
+	plain, _ := hex.DecodeString("2F2F0C1378563412046D32371F1502FD1700002F2F2F2F2F2F2F2F2F2F2F2F2F")
+	// sent by repeater 11111111, with the address of meter 12345678 of KAM in the transport layer, acc 0x07,
+	// two encrypted blocks
+	link, _ := hex.DecodeString("00440204111111110132" + "72")
+	tpl, _ := hex.DecodeString("785634122D2C1B16" + "07" + "00" + "2005")
+	address := []byte{0x2D, 0x2C, 0x78, 0x56, 0x34, 0x12, 0x1B, 0x16}
+	iv := append(append([]byte{}, address...), slices.Repeat([]byte{0x07}, 8)...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plain)
+	b := append(append(link, tpl...), encrypted...)
+	b[0] = byte(len(b) - 1)
+
+	got, err := Decode(b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Error != "" {
+		t.Fatalf("error %q", got.Error)
+	}
+	if got.Link.Id != "11111111" || got.MeterId() != "12345678" || got.Transport.Manufacturer != "KAM" {
+		t.Errorf("link id %s, meter id %s, manufacturer %s", got.Link.Id, got.MeterId(), got.Transport.Manufacturer)
+	}
+	if got.Security == nil || got.Security.Mode != 5 || !got.Security.Decrypted {
+		t.Errorf("security %+v", got.Security)
+	}
+	assertRecords(t, got.Records, []wantRecord{
+		{quantity: "volume", value: 12345.678, unit: "m3"},
+		{quantity: "date time", value: "2008-05-31 23:50"},
+		{quantity: "error flags", value: 0.0},
+	})
+	ids, err := MeterIds(b)
+	if err != nil || !slices.Equal(ids, []string{"11111111", "12345678"}) {
+		t.Errorf("MeterIds = %v, %v", ids, err)
+	}
+}
+
+func TestMeterIds(t *testing.T) {
+	b, _ := ParseHex(omsExample)
+	ids, err := MeterIds(b)
+	if err != nil || !slices.Equal(ids, []string{"12345678"}) {
+		t.Errorf("MeterIds = %v, %v", ids, err)
+	}
+	if _, err = MeterIds(b[:7]); err != ErrTooShort {
+		t.Errorf("MeterIds of 7 bytes: %v, want %v", err, ErrTooShort)
+	}
+}
+
+func TestVariableLength(t *testing.T) {
+	tests := []struct {
+		lvar byte
+		want int
+	}{
+		{0x00, 0}, {0x05, 5}, {0xBF, 191}, {0xC3, 3}, {0xD3, 3}, {0xE2, 2}, {0xF0, 16}, {0xFB, 0},
+	}
+	for _, tt := range tests {
+		if got := variableLength(tt.lvar); got != tt.want {
+			t.Errorf("variableLength(0x%02X) = %d, want %d", tt.lvar, got, tt.want)
+		}
+	}
+}
+
+func TestBcd(t *testing.T) {
+	tests := []struct {
+		data string
+		want int64
+		ok   bool
+	}{
+		{"78563412", 12345678, true},
+		{"0100", 1, true},
+		{"7856F4", -45678, true},
+		{"1A", 0, false},
+	}
+	for _, tt := range tests {
+		b, _ := hex.DecodeString(tt.data)
+		if got, ok := bcd(b); got != tt.want || ok != tt.ok {
+			t.Errorf("bcd(%s) = %d, %v, want %d, %v", tt.data, got, ok, tt.want, tt.ok)
+		}
+	}
+}
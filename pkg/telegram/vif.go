@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telegram
+
+import "fmt"
+
+type valueKind int
+
+const (
+	kindNumber valueKind = iota
+	kindDate
+	kindDateTime
+	kindText
+	kindRaw
+)
+
+// vif is the meaning of a value information field. Numeric values are multiplied with 10^exp.
+type vif struct {
+	quantity string
+	unit     string
+	exp      int
+	kind     valueKind
+}
+
+var durationUnits = []string{"s", "min", "h", "d"}
+
+// primaryVif decodes the primary value information field table of EN 13757-3.
+func primaryVif(v byte) vif {
+	n := int(v & 0x07)
+	nn := int(v & 0x03)
+	switch {
+	case v <= 0x07:
+		return vif{"energy", "Wh", n - 3, kindNumber}
+	case v <= 0x0F:
+		return vif{"energy", "J", n, kindNumber}
+	case v <= 0x17:
+		return vif{"volume", "m3", n - 6, kindNumber}
+	case v <= 0x1F:
+		return vif{"mass", "kg", n - 3, kindNumber}
+	case v <= 0x23:
+		return vif{"on time", durationUnits[nn], 0, kindNumber}
+	case v <= 0x27:
+		return vif{"operating time", durationUnits[nn], 0, kindNumber}
+	case v <= 0x2F:
+		return vif{"power", "W", n - 3, kindNumber}
+	case v <= 0x37:
+		return vif{"power", "J/h", n, kindNumber}
+	case v <= 0x3F:
+		return vif{"volume flow", "m3/h", n - 6, kindNumber}
+	case v <= 0x47:
+		return vif{"volume flow", "m3/min", n - 7, kindNumber}
+	case v <= 0x4F:
+		return vif{"volume flow", "m3/s", n - 9, kindNumber}
+	case v <= 0x57:
+		return vif{"mass flow", "kg/h", n - 3, kindNumber}
+	case v <= 0x5B:
+		return vif{"flow temperature", "°C", nn - 3, kindNumber}
+	case v <= 0x5F:
+		return vif{"return temperature", "°C", nn - 3, kindNumber}
+	case v <= 0x63:
+		return vif{"temperature difference", "K", nn - 3, kindNumber}
+	case v <= 0x67:
+		return vif{"external temperature", "°C", nn - 3, kindNumber}
+	case v <= 0x6B:
+		return vif{"pressure", "bar", nn - 3, kindNumber}
+	case v == 0x6C:
+		return vif{"date", "", 0, kindDate}
+	case v == 0x6D:
+		return vif{"date time", "", 0, kindDateTime}
+	case v == 0x6E:
+		return vif{"heat cost allocation", "", 0, kindNumber}
+	case v <= 0x73 && v >= 0x70:
+		return vif{"averaging duration", durationUnits[nn], 0, kindNumber}
+	case v <= 0x77 && v >= 0x74:
+		return vif{"actuality duration", durationUnits[nn], 0, kindNumber}
+	case v == 0x78:
+		return vif{"fabrication number", "", 0, kindNumber}
+	case v == 0x79:
+		return vif{"enhanced identification", "", 0, kindNumber}
+	case v == 0x7A:
+		return vif{"bus address", "", 0, kindNumber}
+	case v == 0x7E:
+		return vif{"any", "", 0, kindRaw}
+	case v == 0x7F:
+		return vif{"manufacturer specific", "", 0, kindRaw}
+	default:
+		return vif{fmt.Sprintf("reserved vif 0x%02X", v), "", 0, kindRaw}
+	}
+}
+
+// extendedVifFB decodes the first extension table, introduced by VIF 0xFB.
+func extendedVifFB(v byte) vif {
+	n := int(v & 0x01)
+	nn := int(v & 0x03)
+	switch {
+	case v <= 0x01:
+		return vif{"energy", "MWh", n - 1, kindNumber}
+	case v >= 0x08 && v <= 0x09:
+		return vif{"energy", "GJ", n - 1, kindNumber}
+	case v >= 0x10 && v <= 0x11:
+		return vif{"volume", "m3", n + 2, kindNumber}
+	case v >= 0x18 && v <= 0x19:
+		return vif{"mass", "t", n + 2, kindNumber}
+	case v >= 0x1A && v <= 0x1B:
+		return vif{"relative humidity", "%", n - 1, kindNumber}
+	case v >= 0x58 && v <= 0x5B:
+		return vif{"flow temperature", "°F", nn - 3, kindNumber}
+	case v >= 0x5C && v <= 0x5F:
+		return vif{"return temperature", "°F", nn - 3, kindNumber}
+	case v >= 0x60 && v <= 0x63:
+		return vif{"temperature difference", "°F", nn - 3, kindNumber}
+	case v >= 0x64 && v <= 0x67:
+		return vif{"external temperature", "°F", nn - 3, kindNumber}
+	default:
+		return vif{fmt.Sprintf("vif 0xFB 0x%02X", v), "", 0, kindRaw}
+	}
+}
+
+var vifFDNames = map[byte]string{
+	0x08: "access number",
+	0x09: "medium",
+	0x0A: "manufacturer",
+	0x0B: "parameter set id",
+	0x0C: "model version",
+	0x0D: "hardware version",
+	0x0E: "firmware version",
+	0x0F: "software version",
+	0x10: "customer location",
+	0x11: "customer",
+	0x16: "password",
+	0x17: "error flags",
+	0x1A: "digital output",
+	0x1B: "digital input",
+	0x1C: "baud rate",
+	0x3A: "dimensionless",
+	0x60: "reset counter",
+	0x61: "cumulation counter",
+}
+
+// extendedVifFD decodes the second extension table, introduced by VIF 0xFD.
+func extendedVifFD(v byte) vif {
+	switch {
+	case v >= 0x40 && v <= 0x4F:
+		return vif{"voltage", "V", int(v&0x0F) - 9, kindNumber}
+	case v >= 0x50 && v <= 0x5F:
+		return vif{"current", "A", int(v&0x0F) - 12, kindNumber}
+	case v == 0x71:
+		return vif{"rf level", "dBm", 0, kindNumber}
+	case v == 0x74:
+		return vif{"remaining battery lifetime", "d", 0, kindNumber}
+	}
+	if name, ok := vifFDNames[v]; ok {
+		return vif{name, "", 0, kindNumber}
+	}
+	return vif{fmt.Sprintf("vif 0xFD 0x%02X", v), "", 0, kindRaw}
+}
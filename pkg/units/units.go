@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type unit struct {
+	quantity string
+	factor   float64 // to the base unit of the quantity
+	offset   float64 // added after factor, only used for temperatures
+}
+
+// registry contains all units measurements can be converted between. The first unit of each quantity,
+// with factor 1 and no offset, is its base unit.
+var registry = map[string]unit{
+	"m3": {quantity: "volume", factor: 1},
+	"l":  {quantity: "volume", factor: 0.001},
+
+	"m3/h": {quantity: "volume_flow", factor: 1},
+	"l/h":  {quantity: "volume_flow", factor: 0.001},
+
+	"kWh": {quantity: "energy", factor: 1},
+	"Wh":  {quantity: "energy", factor: 0.001},
+	"MWh": {quantity: "energy", factor: 1000},
+	"MJ":  {quantity: "energy", factor: 1 / 3.6},
+	"GJ":  {quantity: "energy", factor: 1000 / 3.6},
+
+	"kW": {quantity: "power", factor: 1},
+	"W":  {quantity: "power", factor: 0.001},
+	"MW": {quantity: "power", factor: 1000},
+
+	"°C": {quantity: "temperature", factor: 1},
+	"°F": {quantity: "temperature", factor: 5.0 / 9, offset: -32 * 5.0 / 9},
+
+	// meters report temperatures in °C and their differences in K, converted without offset
+	"K": {quantity: "temperature_difference", factor: 1},
+
+	"bar":  {quantity: "pressure", factor: 1},
+	"mbar": {quantity: "pressure", factor: 0.001},
+	"Pa":   {quantity: "pressure", factor: 0.00001},
+	"kPa":  {quantity: "pressure", factor: 0.01},
+
+	"s":   {quantity: "duration", factor: 1},
+	"min": {quantity: "duration", factor: 60},
+	"h":   {quantity: "duration", factor: 3600},
+	"d":   {quantity: "duration", factor: 86400},
+
+	"V":  {quantity: "voltage", factor: 1},
+	"mV": {quantity: "voltage", factor: 0.001},
+
+	"A":  {quantity: "current", factor: 1},
+	"mA": {quantity: "current", factor: 0.001},
+}
+
+// Quantity returns the quantity measured in unit, false if the unit is unknown.
+func Quantity(unit string) (string, bool) {
+	u, ok := registry[unit]
+	return u.quantity, ok
+}
+
+// Convert converts value from one unit to another of the same quantity.
+func Convert(value float64, from string, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+	f, ok := registry[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := registry[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.quantity != t.quantity {
+		return 0, fmt.Errorf("unable to convert %s (%s) to %s (%s)", from, f.quantity, to, t.quantity)
+	}
+	base := value*f.factor + f.offset
+	return Round((base - t.offset) / t.factor), nil
+}
+
+// Round removes the noise float arithmetic adds to converted values, e.g. 6.408 m3 is 6408 l, not 6408.000000000001 l.
+func Round(v float64) float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 12, 64), 64)
+	return r
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package units
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value float64
+		from  string
+		to    string
+		want  float64
+		err   bool
+	}{
+		{6.408, "m3", "l", 6408, false},
+		{1500, "l", "m3", 1.5, false},
+		{1, "MWh", "kWh", 1000, false},
+		{3.6, "GJ", "MWh", 1, false},
+		{100, "°C", "K", 0, true},
+		{212, "°F", "°C", 100, false},
+		{1, "bar", "kPa", 100, false},
+		{2, "h", "min", 120, false},
+		{5, "kW", "kW", 5, false},
+		{1, "m3", "kWh", 0, true},
+		{1, "gallon", "l", 0, true},
+		{1, "l", "gallon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("Convert(%v, %s, %s) = %v, %v, want %v, error %v", tt.value, tt.from, tt.to, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		unit string
+		want string
+		ok   bool
+	}{
+		{"m3", "volume", true},
+		{"kWh", "energy", true},
+		{"m3/h", "volume_flow", true},
+		{"°C", "temperature", true},
+		{"K", "temperature_difference", true},
+		{"", "", false},
+		{"gallon", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := Quantity(tt.unit); got != tt.want || ok != tt.ok {
+			t.Errorf("Quantity(%q) = %q, %v, want %q, %v", tt.unit, got, ok, tt.want, tt.ok)
+		}
+	}
+}
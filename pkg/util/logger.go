@@ -18,6 +18,7 @@ package util
 
 import (
 	structlogger "github.com/SENERGY-Platform/go-service-base/struct-logger"
+	"io"
 	"log/slog"
 	"os"
 	"runtime/debug"
@@ -28,6 +29,11 @@ import (
 var Logger *slog.Logger
 
 func InitStructLogger(level string) {
+	InitStructLoggerTo(level, os.Stdout)
+}
+
+// InitStructLoggerTo initializes Logger to write to w, e.g. to keep stdout free for command output.
+func InitStructLoggerTo(level string, w io.Writer) {
 	if Logger == nil {
 		info, ok := debug.ReadBuildInfo()
 		project := ""
@@ -46,7 +52,7 @@ func InitStructLogger(level string) {
 				TimeUtc:    true,
 				AddMeta:    true,
 			},
-			os.Stdout,
+			w,
 			org,
 			project,
 		)
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/alarm"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/telegram"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// handleTelegramStatus raises and clears the alarms of the status byte of a telegram. The status is part of the
+// unencrypted transport layer header, so no key is required.
+func (w *WmbusLogForwarder) handleTelegramStatus(radio config.Radio, msg *model.EncryptedMessage) {
+	b, err := telegram.ParseHex(msg.Telegram)
+	if err != nil {
+		return
+	}
+	t, err := telegram.Decode(b, nil)
+	if err != nil || t.Transport == nil || t.Transport.Header == "none" {
+		return
+	}
+	alerts := w.alarms.Update(msg.MeterId, alarm.SourceTelegram, alarm.FromStatusByte(t.Transport.Status), *msg.Timestamp)
+	w.sendAlerts(radio, msg.MeterId, alerts)
+}
+
+// handleReadingStatus raises and clears the alarms of the status fields of a wmbusmeters reading received at t.
+func (w *WmbusLogForwarder) handleReadingStatus(radio config.Radio, meterId string, j map[string]any, t time.Time) {
+	alarms, ok := alarm.FromReading(j)
+	if !ok {
+		return
+	}
+	w.sendAlerts(radio, meterId, w.alarms.Update(meterId, alarm.SourceReading, alarms, t))
+}
+
+// sendAlerts sends alerts right away, regardless of aggregation. Alerts are events of the meter if its device
+// is known, of the radio otherwise.
+func (w *WmbusLogForwarder) sendAlerts(radio config.Radio, meterId string, alerts []model.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	device := radio.Id
+	if _, ok := w.deviceManager.Get(meterId); ok {
+		device = meterId
+	}
+	meter, _ := w.cfg().Meter(meterId)
+	for _, a := range alerts {
+		a.Annotation = meter.Annotation()
+		util.Logger.Warn("alert", "meter_id", meterId, "type", a.Type, "state", a.State, "severity", a.Severity, "since", a.Since)
+		err := sink.MarshalAndSendEvent(w.sink, device, alertsServiceId, a)
+		if err != nil {
+			util.Logger.Error("unable to send event ("+alertsServiceId+")", "err", err)
+		}
+	}
+}
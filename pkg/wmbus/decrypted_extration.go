@@ -17,21 +17,27 @@
 package wmbus
 
 import (
+	"cmp"
 	"encoding/json"
 	"os"
-	"strings"
+	"path/filepath"
+	"time"
 
-	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/logrotate"
-	nimbusmgw "github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/nimbus_mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/discovery"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/filter"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/measurement"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
 	"github.com/fsnotify/fsnotify"
-	"github.com/nxadm/tail"
 )
 
-func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingDirectory() {
-	err := os.MkdirAll(w.cfg.WmbusMeterReadingsDir, 0744)
+func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingDirectory(radio config.Radio, source config.Source) {
+	dir := source.Path
+	err := os.MkdirAll(dir, 0744)
 	if err != nil {
-		util.Logger.Error("unable to create wmbusmeters meter reading dir", "dir", w.cfg.WmbusMeterReadingsDir, "err", err)
+		util.Logger.Error("unable to create wmbusmeters meter reading dir", "dir", dir, "err", err)
 		w.cf()
 		return
 	}
@@ -40,9 +46,9 @@ func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingDirectory() {
 		defer w.wg.Done()
 
 		// check all files in the meter readings dir
-		dirEntries, err := os.ReadDir(w.cfg.WmbusMeterReadingsDir)
+		dirEntries, err := os.ReadDir(dir)
 		if err != nil {
-			util.Logger.Error("unable to stat wmbusmeters meter reading dir", "dir", w.cfg.WmbusMeterReadingsDir, "err", err)
+			util.Logger.Error("unable to stat wmbusmeters meter reading dir", "dir", dir, "err", err)
 			w.cf()
 			return
 		}
@@ -50,23 +56,23 @@ func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingDirectory() {
 			if dirEntry.IsDir() {
 				continue
 			}
-			w.handleWmbusmetersMeterReadingsFile(w.cfg.WmbusMeterReadingsDir + string(os.PathSeparator) + dirEntry.Name())
+			w.handleWmbusmetersMeterReadingsFile(radio, source, filepath.Join(dir, dirEntry.Name()))
 		}
 
 		// check for newly created files in the meter readings dir
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
-			util.Logger.Error("unable to watch wmbusmeters meter reading dir", "dir", w.cfg.WmbusMeterReadingsDir, "err", err)
+			util.Logger.Error("unable to watch wmbusmeters meter reading dir", "dir", dir, "err", err)
 			w.cf()
 			return
 		}
 		defer watcher.Close()
-		err = watcher.Add(w.cfg.WmbusMeterReadingsDir)
+		err = watcher.Add(dir)
 		for {
 			select {
 			case event := <-watcher.Events:
 				if event.Op.Has(fsnotify.Create) {
-					w.handleWmbusmetersMeterReadingsFile(event.Name)
+					w.handleWmbusmetersMeterReadingsFile(radio, source, event.Name)
 				}
 			case <-w.ctx.Done():
 				return
@@ -75,85 +81,193 @@ func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingDirectory() {
 	}()
 }
 
-func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingsFile(file string) {
-	// add log rotation, since not done by wmbusmeters
-	w.logRotater.AddFiles(file)
-	filenameParts := strings.Split(file, string(os.PathSeparator))
-	seekio, err := logrotate.NewSeekIO(w.cfg.SeekDir+string(os.PathSeparator)+filenameParts[len(filenameParts)-1], w.ctx, w.wg)
+// handleWmbusmetersMeterReadingsFile forwards the readings of a file written by wmbusmeters. The format is
+// detected from the first line of the file.
+func (w *WmbusLogForwarder) handleWmbusmetersMeterReadingsFile(radio config.Radio, source config.Source, file string) {
+	reader := &readingFileReader{source: source, file: file}
+	w.tailFile(file, func(text string) {
+		msg, j, raw := reader.read(text)
+		switch {
+		case msg != nil:
+			w.handleEncryptedMessage(radio, msg)
+		case j != nil:
+			w.handleMeterReading(radio, file, j, raw)
+		}
+	})
+}
+
+// handleMeterReadingText forwards a wmbusmeters JSON reading received from source.
+func (w *WmbusLogForwarder) handleMeterReadingText(radio config.Radio, source string, text string) {
+	j := map[string]any{}
+	err := json.Unmarshal([]byte(text), &j)
 	if err != nil {
-		w.cf()
+		util.Logger.Error("unable to unmarshal meter reading line", "file", source, "line", text, "err", err)
 		return
 	}
-	seekinfo := seekio.Get()
-	seekinfo, err = ensureSeekInfoInBounds(file, seekinfo)
-	if err != nil {
-		util.Logger.Error("unable to ensureSeekInfoInBounds", "file", file, "err", err)
-		w.cf()
-		return
+	w.handleMeterReading(radio, source, j, []byte(text))
+}
+
+// handleMeterReading forwards a decrypted wmbusmeters reading j, raw is its original encoding, and reports
+// whether it was forwarded. The meter's device is registered with the first reading.
+func (w *WmbusLogForwarder) handleMeterReading(radio config.Radio, file string, j map[string]any, raw []byte) bool {
+	id, ok := j["id"]
+	if !ok {
+		util.Logger.Error("unable to read meter reading: missing field id", "file", file, "json", j)
+		return false
+	}
+	idStr, ok := id.(string)
+	if !ok {
+		util.Logger.Error("unable to read meter reading: field id is not string", "file", file, "json", j)
+		return false
+	}
+	if !config.ValidMeterId(idStr) {
+		// the id names the meter's device and archive directory
+		util.Logger.Error("unable to read meter reading: field id is not a meter id", "file", file, "json", j)
+		return false
 	}
 
-	w.wg.Add(1)
-	go func() {
-		defer w.wg.Done()
-		t, err := tail.TailFile(file, tail.Config{
-			Follow:        true,
-			Logger:        tail.DiscardingLogger,
-			ReOpen:        true,
-			CompleteLines: true,
-			Location:      seekinfo,
-		})
-		if err != nil {
-			util.Logger.Error("Error tailing file", "error", err)
-			w.cf()
-		}
-		defer t.Cleanup()
+	name, ok := j["name"]
+	if !ok {
+		util.Logger.Error("unable to read meter reading: missing field name", "file", file, "json", j)
+		return false
+	}
+	nameStr, ok := name.(string)
+	if !ok {
+		util.Logger.Error("unable to read meter reading: field name is not string", "file", file, "json", j)
+		return false
+	}
 
-		for {
-			select {
-			case line := <-t.Lines:
-				seekio.Set(&line.SeekInfo)
-				if line == nil {
-					continue
-				}
-				j := map[string]any{}
-				err = json.Unmarshal([]byte(line.Text), &j)
-				if err != nil {
-					util.Logger.Error("unable to unmarshal meter reading line", "file", file, "line", line, "err", err)
-					continue
-				}
-				id, ok := j["id"]
-				if !ok {
-					util.Logger.Error("unable to read meter reading: missing field id", "file", file, "json", j)
-					continue
-				}
-				idStr, ok := id.(string)
-				if !ok {
-					util.Logger.Error("unable to read meter reading: field id is not string", "file", file, "json", j)
-					continue
-				}
+	if _, err := time.Parse(time.RFC3339, stringField(j, "timestamp")); err != nil && w.replay {
+		util.Logger.Debug("dropped replayed meter reading without timestamp", "meter_id", idStr)
+		return false
+	}
 
-				name, ok := j["name"]
-				if !ok {
-					util.Logger.Error("unable to read meter reading: missing field name", "file", file, "json", j)
-					continue
-				}
-				nameStr, ok := name.(string)
-				if !ok {
-					util.Logger.Error("unable to read meter reading: field name is not string", "file", file, "json", j)
-					continue
-				}
+	info := w.observeMeter(idStr, func(info *meterInfo) {
+		info.Name = nameStr
+		info.Driver = cmp.Or(stringField(j, "driver"), stringField(j, "meter"), info.Driver)
+	})
+	subject := filter.Subject{
+		MeterId:      idStr,
+		Manufacturer: info.Manufacturer,
+		Type:         info.Type,
+		Driver:       info.Driver,
+	}
+	if subject.Driver == "" {
+		// older wmbusmeters versions name the driver field meter
+		subject.Driver = stringField(j, "meter")
+	}
+	if rssi, ok := j["rssi_dbm"].(float64); ok {
+		subject.RSSI = &rssi
+	}
+	if !w.admit(discovery.Observation{Subject: subject, Name: nameStr, Radio: radio.Id}) {
+		return false
+	}
 
-				util.Logger.Debug("Got decrypted message", "meter_id", idStr, "name", nameStr)
-				w.deviceManager.AddIdempotent(&nimbusmgw.Device{
-					Id:   idStr,
-					Name: nameStr,
-				})
-				err = w.mgwClient.SendEvent(idStr, decryptedServiceId, []byte(line.Text))
-				util.Logger.Error("unable to send event ("+decryptedServiceId+")", "err", err)
-				continue
-			case <-w.ctx.Done():
-				return
-			}
+	util.Logger.Debug("Got decrypted message", "meter_id", idStr, "name", nameStr)
+	w.deviceManager.AddIdempotent(w.meterDevice(idStr, nameStr))
+	w.deviceManager.Touch(idStr, radio.Id)
+
+	cfg := w.cfg()
+	res := w.schemas.Load().Validate(j)
+	w.reportSchemaResult(radio, idStr, res)
+	if len(res.Invalid) > 0 && cfg.Pipeline.RejectInvalid {
+		util.Logger.Warn("rejected invalid meter reading", "meter_id", idStr, "issues", res.Invalid)
+		return false
+	}
+
+	enriched := res.Changed
+	if _, err := time.Parse(time.RFC3339, stringField(j, "timestamp")); err != nil {
+		// older wmbusmeters versions do not stamp readings, the reading was written just now
+		j["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+		enriched = true
+	}
+
+	meter, _ := cfg.Meter(idStr)
+	reading := measurement.Map(j, cfg.Pipeline.Mappings)
+	reading.Annotation = meter.Annotation()
+	if reading.Timestamp != nil {
+		w.handleReadingStatus(radio, idStr, j, *reading.Timestamp)
+	}
+	for _, err := range measurement.Convert(reading, cfg.Pipeline.Units) {
+		util.Logger.Warn("unable to convert measurement", "meter_id", idStr, "err", err)
+	}
+	if cfg.Pipeline.Plausibility.Enabled {
+		checked := w.plausibility.Check(reading, cfg.Pipeline.Plausibility)
+		for _, field := range checked.Withheld {
+			delete(j, field)
+			enriched = true
 		}
-	}()
+		if len(checked.Flags) > 0 {
+			j["plausibility"] = checked.Flags
+			enriched = true
+		}
+	}
+
+	if reading.MeterTime != nil && reading.Timestamp != nil {
+		skew := w.deviceManager.ObserveClock(idStr, *reading.MeterTime, *reading.Timestamp).Seconds()
+		reading.ClockSkew = &skew
+		j["clock_skew"] = skew
+		enriched = true
+	}
+	if reading.Annotation != nil {
+		j["annotation"] = reading.Annotation
+		enriched = true
+	}
+	payload := raw
+	if enriched {
+		var err error
+		payload, err = json.Marshal(j)
+		if err != nil {
+			util.Logger.Error("unable to marshal enriched meter reading", "meter_id", idStr, "err", err)
+			return false
+		}
+	}
+	if w.archive != nil {
+		t := time.Now()
+		if reading.Timestamp != nil {
+			t = *reading.Timestamp
+		}
+		err := w.archive.Write(idStr, t, payload)
+		if err != nil {
+			util.Logger.Error("unable to archive meter reading", "meter_id", idStr, "err", err)
+		}
+	}
+	policy := aggregation(cfg, idStr)
+	if w.aggregator != nil && policy.Interval > 0 {
+		w.aggregator.Add(idStr, policy, payload, reading)
+		return true
+	}
+	w.send(idStr, payload, reading)
+	return true
+}
+
+// send forwards a meter reading, payload is its decrypted event.
+func (w *WmbusLogForwarder) send(meterId string, payload []byte, reading *model.Reading) {
+	err := w.sink.SendEvent(meterId, decryptedServiceId, payload)
+	if err != nil {
+		util.Logger.Error("unable to send event ("+decryptedServiceId+")", "err", err)
+	}
+	if len(reading.Measurements) == 0 {
+		return
+	}
+	err = sink.MarshalAndSendEvent(w.sink, meterId, measurementsServiceId, reading)
+	if err != nil {
+		util.Logger.Error("unable to send event ("+measurementsServiceId+")", "err", err)
+	}
+	if !w.cfg().Pipeline.Derived.Enabled {
+		return
+	}
+	d := w.derived.Derive(reading, w.location.Load())
+	if d == nil {
+		return
+	}
+	err = sink.MarshalAndSendEvent(w.sink, meterId, derivedServiceId, d)
+	if err != nil {
+		util.Logger.Error("unable to send event ("+derivedServiceId+")", "err", err)
+	}
+}
+
+func stringField(j map[string]any, key string) string {
+	s, _ := j[key].(string)
+	return s
 }
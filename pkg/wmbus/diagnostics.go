@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/schema"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const (
+	DiagnosticDecryptionFailed = "decryption_failed"
+	DiagnosticBadCRC           = "bad_crc"
+	DiagnosticUnknownDriver    = "unknown_driver"
+	DiagnosticDriverMismatch   = "driver_mismatch"
+	DiagnosticDecodeFailed     = "decode_failed"
+	DiagnosticDongleLost       = "dongle_lost"
+	DiagnosticMalformedBlock   = "malformed_block"
+	DiagnosticInvalidReading   = "invalid_reading"
+	DiagnosticSchemaDrift      = "schema_drift"
+	DiagnosticWarning          = "warning"
+	DiagnosticError            = "error"
+)
+
+const (
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+type diagnosticRule struct {
+	typ      string
+	severity string
+	pattern  *regexp.Regexp
+}
+
+// diagnosticRules classify wmbusmeters warnings and errors, the first matching rule wins.
+var diagnosticRules = []diagnosticRule{
+	{DiagnosticDecryptionFailed, severityError, regexp.MustCompile(`(?i)decrypt\w* .*(fail|wrong|incorrect)|(fail|wrong|incorrect)\w* .*(decrypt|key)`)},
+	{DiagnosticBadCRC, severityWarning, regexp.MustCompile(`(?i)\bcrc\b.*(fail|bad|error|invalid)|(bad|invalid|wrong) crc`)},
+	{DiagnosticDriverMismatch, severityWarning, regexp.MustCompile(`(?i)did not match the selected driver|correct driver is`)},
+	{DiagnosticUnknownDriver, severityWarning, regexp.MustCompile(`(?i)(no such|unknown|not a valid( meter)?) driver|driver: unknown`)},
+	{DiagnosticDongleLost, severityError, regexp.MustCompile(`(?i)(device|dongle|serial|tty|usb).*\b(lost|gone|disappeared|no longer available|not found)|no wmbus device`)},
+	{DiagnosticDecodeFailed, severityWarning, regexp.MustCompile(`(?i)(decod|pars)\w* .*fail|fail\w* .*(decod|pars)|too short|bad length`)},
+	{DiagnosticError, severityError, regexp.MustCompile(`(?i)\berror\b`)},
+	{DiagnosticWarning, severityWarning, regexp.MustCompile(`(?i)\bwarning\b`)},
+}
+
+// wmbusmeters prefixes its messages with the component in parentheses, e.g. (wmbus) or (serial)
+var componentPattern = regexp.MustCompile(`^\([a-z0-9_]+\) `)
+
+var diagnosticMeterIdPattern = regexp.MustCompile(`(?i)\b(?:id:?|from:?|meter:?) ?([0-9a-f]{8})\b`)
+
+// suppress repetitions of the same diagnostic, e.g. a bad crc for every telegram of a meter
+const diagnosticRepeatInterval = time.Minute
+
+// diagnose returns the diagnostic a log line reports, nil if it is no warning or error. Only lines marked by
+// wmbusmeters as coming from one of its components or as warning or error are considered.
+func diagnose(line string) *model.Diagnostic {
+	var ts *time.Time
+	if match := logTimestampPattern.FindStringSubmatch(line); match != nil {
+		line = line[len(match[0]):]
+		if t, err := time.ParseInLocation(logTimestampLayout, match[1], time.Local); err == nil {
+			ts = &t
+		}
+	}
+	text := strings.TrimSpace(line)
+	upper := strings.ToUpper(text)
+	if !componentPattern.MatchString(text) && !strings.HasPrefix(upper, "WARNING") && !strings.HasPrefix(upper, "ERROR") {
+		return nil
+	}
+	for _, rule := range diagnosticRules {
+		if !rule.pattern.MatchString(text) {
+			continue
+		}
+		d := &model.Diagnostic{
+			Type:      rule.typ,
+			Severity:  rule.severity,
+			Message:   text,
+			Timestamp: ts,
+		}
+		if match := diagnosticMeterIdPattern.FindStringSubmatch(text); match != nil {
+			d.MeterId = strings.ToLower(match[1])
+		}
+		return d
+	}
+	return nil
+}
+
+// diagnostics sends diagnostics to the radio devices, suppressing repetitions.
+type diagnostics struct {
+	sent map[string]time.Time
+	mux  sync.Mutex
+}
+
+func (w *WmbusLogForwarder) sendDiagnostic(radio config.Radio, d *model.Diagnostic) {
+	if w.replay {
+		util.Logger.Debug("diagnostic of replayed line", "radio", radio.Id, "type", d.Type, "meter_id", d.MeterId, "message", d.Message)
+		return
+	}
+	if d.Timestamp == nil {
+		now := time.Now()
+		d.Timestamp = &now
+	}
+	key := radio.Id + "/" + d.Type + "/" + d.MeterId
+	w.diagnostics.mux.Lock()
+	if w.diagnostics.sent == nil {
+		w.diagnostics.sent = map[string]time.Time{}
+	}
+	last, ok := w.diagnostics.sent[key]
+	if ok && d.Timestamp.Sub(last) < diagnosticRepeatInterval && !d.Timestamp.Before(last) {
+		w.diagnostics.mux.Unlock()
+		return
+	}
+	w.diagnostics.sent[key] = *d.Timestamp
+	w.diagnostics.mux.Unlock()
+
+	util.Logger.Warn("diagnostic", "radio", radio.Id, "type", d.Type, "meter_id", d.MeterId, "message", d.Message)
+	err := sink.MarshalAndSendEvent(w.sink, radio.Id, diagnosticsServiceId, d)
+	if err != nil {
+		util.Logger.Error("unable to send event ("+diagnosticsServiceId+")", "err", err)
+	}
+}
+
+// reportSchemaResult reports invalid fields of a reading and schema drifts, the latter once per driver and field.
+func (w *WmbusLogForwarder) reportSchemaResult(radio config.Radio, meterId string, res schema.Result) {
+	if len(res.Invalid) > 0 {
+		w.sendDiagnostic(radio, &model.Diagnostic{
+			Type:     DiagnosticInvalidReading,
+			Severity: severityWarning,
+			MeterId:  meterId,
+			Message:  "invalid fields: " + issuesText(res.Invalid),
+		})
+	}
+	drifts := []schema.Issue{}
+	for _, issue := range res.Drift {
+		if _, reported := w.schemaDrift.LoadOrStore(res.Driver+"/"+issue.Field+"/"+issue.Problem, true); !reported {
+			drifts = append(drifts, issue)
+		}
+	}
+	if len(drifts) == 0 {
+		return
+	}
+	util.Logger.Warn("meter reading deviates from driver schema", "driver", res.Driver, "meter_id", meterId, "drift", drifts)
+	w.sendDiagnostic(radio, &model.Diagnostic{
+		Type:     DiagnosticSchemaDrift,
+		Severity: severityWarning,
+		MeterId:  meterId,
+		Message:  "readings of driver " + res.Driver + " deviate from schema: " + issuesText(drifts),
+	})
+}
+
+func issuesText(issues []schema.Issue) string {
+	parts := make([]string, len(issues))
+	for i, issue := range issues {
+		parts[i] = issue.Field + " " + issue.Problem
+	}
+	return strings.Join(parts, ", ")
+}
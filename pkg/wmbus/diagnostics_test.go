@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		line     string
+		typ      string // no diagnostic if empty
+		severity string
+		meterId  string
+	}{
+		{
+			line:     "(wmbus) WARNING! decrypted content failed check, did you use the correct decryption key? Permanently ignoring telegrams from id: 12345678 mfct: (KAM) Kamstrup Energi (0x2c2d) type: Cold water meter (0x16) ver: 0x1b",
+			typ:      DiagnosticDecryptionFailed,
+			severity: severityError,
+			meterId:  "12345678",
+		},
+		{"(wmbus) WARNING!! decryption failed, wrong key for meter 0A1B2C3D?", DiagnosticDecryptionFailed, severityError, "0a1b2c3d"},
+		{"(wmbus) telegram from 87654321 ignored due to bad crc", DiagnosticBadCRC, severityWarning, "87654321"},
+		{"(wmbus) WARNING! crc check failed, telegram from: 87654321", DiagnosticBadCRC, severityWarning, "87654321"},
+		{"(meter) water: meter detection did not match the selected driver multical21! correct driver is: iperl", DiagnosticDriverMismatch, severityWarning, ""},
+		{`(meter) Not a valid meter driver "multical99"`, DiagnosticUnknownDriver, severityWarning, ""},
+		{"(serial) device /dev/ttyUSB0 is gone", DiagnosticDongleLost, severityError, ""},
+		{"(main) No wmbus device detected, waiting for a device to be plugged in.", DiagnosticDongleLost, severityError, ""},
+		{"(dvparser) cannot parse dv entry, telegram too short", DiagnosticDecodeFailed, severityWarning, ""},
+		{"(wmbus) decoding of telegram from 12345678 failed", DiagnosticDecodeFailed, severityWarning, "12345678"},
+		{"(config) error in config file, unknown key foo", DiagnosticError, severityError, ""},
+		{"ERROR: could not open /dev/ttyAMA0", DiagnosticError, severityError, ""},
+		{"Warning: the meter 12345678 sends unusual telegrams", DiagnosticWarning, severityWarning, "12345678"},
+		{"(wmbus) WARNING! telegram from meter 12345678 has an unknown format", DiagnosticWarning, severityWarning, "12345678"},
+
+		// not diagnostics
+		{line: "(main) some informational message"},
+		{line: "(wmbus) received telegram from 12345678"},
+		{line: "Started config rtlwmbus listening on all"},
+		{line: "No meters configured, printing id:s of all telegrams heard!"},
+		{line: "Received telegram from: 12345678"},
+		{line: "          manufacturer: (KAM) Kamstrup Energi (0x2c2d)"},
+		{line: "                driver: unknown"},
+		{line: "telegram=|_2D442D2C785634121B16|"},
+		{line: `{"id":"12345678","status":"ERROR","current_status":"DRY"}`},
+		{line: "kitchen;12345678;1.5;error"},
+		{line: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			d := diagnose(tt.line)
+			if tt.typ == "" {
+				if d != nil {
+					t.Fatalf("unexpected diagnostic %+v", d)
+				}
+				return
+			}
+			if d == nil {
+				t.Fatal("no diagnostic")
+			}
+			if d.Type != tt.typ || d.Severity != tt.severity || d.MeterId != tt.meterId {
+				t.Errorf("got %s/%s/%q, want %s/%s/%q", d.Type, d.Severity, d.MeterId, tt.typ, tt.severity, tt.meterId)
+			}
+			if d.Timestamp != nil {
+				t.Errorf("timestamp %v for line without timestamp", d.Timestamp)
+			}
+		})
+	}
+}
+
+func TestDiagnoseTimestamp(t *testing.T) {
+	d := diagnose("[2024-03-01_12:00:00] (wmbus) telegram from 87654321 ignored due to bad crc")
+	if d == nil {
+		t.Fatal("no diagnostic")
+	}
+	want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	if d.Timestamp == nil || !d.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", d.Timestamp, want)
+	}
+	if d.Message != "(wmbus) telegram from 87654321 ignored due to bad crc" {
+		t.Errorf("message = %q, want line without timestamp", d.Message)
+	}
+}
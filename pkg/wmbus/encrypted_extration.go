@@ -17,19 +17,59 @@
 package wmbus
 
 import (
-	"os"
+	"cmp"
+	"errors"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/logrotate"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/discovery"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/filter"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
-	nimbusmgw "github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/nimbus_mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/telegram"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
-	"github.com/nxadm/tail"
 )
 
+type blockState int
+
+const (
+	stateIdle       blockState = iota // waiting for the next block
+	stateBlock                        // collecting the fields of a block
+	stateDiscarding                   // skipping the rest of a malformed block
+)
+
+// blocks are written at once, a block without telegram after this time was truncated
+const blockTimeout = 10 * time.Second
+
+// lines kept to describe malformed blocks
+const maxBlockLines = 16
+
+// encryptedExtractor collects the lines of a wmbusmeters log block, starting with "Received telegram from:"
+// and ending with the telegram, into a message.
 type encryptedExtractor struct {
-	msg *model.EncryptedMessage
+	state   blockState
+	msg     *model.EncryptedMessage
+	started time.Time
+	lines   []string
+	now     func() time.Time // time.Now if nil
+}
+
+// MalformedBlockError describes a discarded log block.
+type MalformedBlockError struct {
+	Reason  string
+	MeterId string
+	Lines   []string
+}
+
+func (e *MalformedBlockError) Error() string {
+	if e.MeterId == "" {
+		return "malformed block: " + e.Reason
+	}
+	return "malformed block of meter " + e.MeterId + ": " + e.Reason
 }
 
 const (
@@ -44,98 +84,216 @@ const (
 	telegramSuffix = "|"
 )
 
-func (w *WmbusLogForwarder) handleWmbusmetersLogFile() {
-	// add log rotation, since not done by wmbusmeters
-	w.logRotater.AddFiles(w.cfg.WmbusLogFile)
-	filenameParts := strings.Split(w.cfg.WmbusLogFile, string(os.PathSeparator))
-	seekio, err := logrotate.NewSeekIO(w.cfg.SeekDir+string(os.PathSeparator)+filenameParts[len(filenameParts)-1], w.ctx, w.wg)
+// logtimestamps of wmbusmeters prefixes lines with their local time, e.g. [2024-03-01_12:00:00]
+var logTimestampPattern = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2}_\d{2}:\d{2}:\d{2}(?:\.\d+)?)\] `)
+
+const logTimestampLayout = "2006-01-02_15:04:05"
+
+func (w *WmbusLogForwarder) handleWmbusmetersLogFile(radio config.Radio, file string) {
+	encryptedExtractor := encryptedExtractor{}
+	w.tailFile(file, func(text string) {
+		msg := w.handleLogLine(radio, file, &encryptedExtractor, text)
+		if msg == nil {
+			return
+		}
+		w.handleEncryptedMessage(radio, msg)
+	})
+}
+
+// handleEncryptedMessage forwards a telegram received by radio and reports whether it was admitted.
+func (w *WmbusLogForwarder) handleEncryptedMessage(radio config.Radio, msg *model.EncryptedMessage) bool {
+	if !config.ValidMeterId(msg.MeterId) {
+		util.Logger.Error("dropped telegram of invalid meter id", "meter_id", msg.MeterId)
+		return false
+	}
+	if msg.Timestamp == nil && w.replay {
+		util.Logger.Debug("dropped replayed telegram without timestamp", "meter_id", msg.MeterId)
+		return false
+	}
+	subject := filter.Subject{
+		MeterId:      msg.MeterId,
+		Manufacturer: filter.Manufacturer(msg.Manufacturer),
+		Type:         filter.Type(msg.Type),
+		Driver:       msg.Driver,
+		RSSI:         rssiOf(msg),
+	}
+	w.observeMeter(msg.MeterId, func(info *meterInfo) {
+		info.Manufacturer = cmp.Or(subject.Manufacturer, info.Manufacturer)
+		info.Type = cmp.Or(subject.Type, info.Type)
+		info.Driver = cmp.Or(subject.Driver, info.Driver)
+	})
+	if !w.admit(discovery.Observation{Subject: subject, Radio: radio.Id}) {
+		return false
+	}
+	if msg.Timestamp == nil {
+		// wmbusmeters logs without timestamps, the telegram was received just now
+		now := time.Now()
+		msg.Timestamp = &now
+	}
+	if strings.HasPrefix(msg.Driver, "unknown") {
+		w.sendDiagnostic(radio, &model.Diagnostic{
+			Type:      DiagnosticUnknownDriver,
+			Severity:  severityWarning,
+			MeterId:   msg.MeterId,
+			Message:   "no driver for meter " + msg.MeterId,
+			Timestamp: msg.Timestamp,
+		})
+	}
+	util.Logger.Debug("Got message", "meter_id", msg.MeterId, "rssi", msg.RSSI)
+	w.deviceManager.Touch(msg.MeterId, radio.Id)
+	w.handleTelegramStatus(radio, msg)
+	cfg := w.cfg()
+	if m, ok := cfg.Meter(msg.MeterId); ok {
+		msg.Annotation = m.Annotation()
+	}
+	if policy := aggregation(cfg, msg.MeterId); w.aggregator != nil && policy.Interval > 0 {
+		w.aggregator.AddTelegram(radio.Id, policy, msg)
+		return true
+	}
+	w.sendEncrypted(radio.Id, msg)
+	return true
+}
+
+func (w *WmbusLogForwarder) sendEncrypted(radioId string, msg *model.EncryptedMessage) {
+	err := sink.MarshalAndSendEvent(w.sink, radioId, encryptedServiceId, msg)
 	if err != nil {
-		w.cf()
-		return
+		util.Logger.Error("unable to send event ("+encryptedServiceId+")", "err", err)
 	}
+}
 
-	seekinfo := seekio.Get()
-	seekinfo, err = ensureSeekInfoInBounds(w.cfg.WmbusLogFile, seekinfo)
+// handleLogLine passes a line of the wmbusmeters log to the diagnostics or to the block extractor and returns
+// the message completed by it.
+func (w *WmbusLogForwarder) handleLogLine(radio config.Radio, file string, e *encryptedExtractor, text string) *model.EncryptedMessage {
+	if d := diagnose(text); d != nil {
+		w.sendDiagnostic(radio, d)
+		return nil
+	}
+	msg, err := e.handleLine(text)
 	if err != nil {
-		util.Logger.Error("unable to ensureSeekInfoInBounds", "file", w.cfg.WmbusLogFile, "err", err)
-		w.cf()
-		return
+		w.reportMalformed(radio, file, err)
 	}
+	return msg
+}
 
-	w.deviceManager.AddIdempotent(&nimbusmgw.Device{
-		Id:           w.cfg.NimbusId,
-		Name:         w.cfg.NimbusName,
-		DeviceTypeId: w.cfg.NimbusDeviceTypeId,
+func (w *WmbusLogForwarder) reportMalformed(radio config.Radio, file string, err error) {
+	var mbe *MalformedBlockError
+	if !errors.As(err, &mbe) {
+		util.Logger.Warn("unable to read log block", "file", file, "err", err)
+		return
+	}
+	util.Logger.Warn("discarded malformed log block", "file", file, "meter_id", mbe.MeterId, "reason", mbe.Reason, "lines", mbe.Lines)
+	w.sendDiagnostic(radio, &model.Diagnostic{
+		Type:     DiagnosticMalformedBlock,
+		Severity: severityWarning,
+		MeterId:  mbe.MeterId,
+		Message:  mbe.Error(),
 	})
+}
 
-	w.wg.Add(1)
-	go func() {
-		defer w.wg.Done()
-		t, err := tail.TailFile(w.cfg.WmbusLogFile, tail.Config{
-			Follow:        true,
-			Logger:        tail.DiscardingLogger,
-			ReOpen:        true,
-			CompleteLines: true,
-			Location:      seekinfo,
-		})
-		if err != nil {
-			util.Logger.Error("Error tailing file", "error", err)
-			w.cf()
-		}
-		defer t.Cleanup()
-
-		encryptedExtractor := encryptedExtractor{}
-		for {
-			select {
-			case line := <-t.Lines:
-				seekio.Set(&line.SeekInfo)
-				if line == nil {
-					continue
-				}
-				msg := encryptedExtractor.handleLine(line.Text)
-				if msg == nil {
-					continue
-				}
-				util.Logger.Debug("Got message", "meter_id", msg.MeterId, "rssi", msg.RSSI)
-				err = w.mgwClient.MarshalAndSendEvent(w.cfg.NimbusId, encryptedServiceId, msg)
-				if err != nil {
-					util.Logger.Error("unable to send event ("+encryptedServiceId+")", "err", err)
-					continue
-				}
-
-			case <-w.ctx.Done():
-				return
-			}
-		}
-	}()
+func rssiOf(msg *model.EncryptedMessage) *float64 {
+	if msg.RSSIUnit == "" && msg.RSSI == 0 {
+		return nil
+	}
+	return &msg.RSSI
 }
 
-func (e *encryptedExtractor) handleLine(line string) *model.EncryptedMessage {
-	if e.msg == nil {
-		e.msg = &model.EncryptedMessage{}
+func (e *encryptedExtractor) handleLine(line string) (*model.EncryptedMessage, error) {
+	now := time.Now()
+	if e.now != nil {
+		now = e.now()
+	}
+	var ts *time.Time
+	if match := logTimestampPattern.FindStringSubmatch(line); match != nil {
+		line = line[len(match[0]):]
+		if t, err := time.ParseInLocation(logTimestampLayout, match[1], time.Local); err == nil {
+			ts = &t
+		}
 	}
 
 	if after, ok := strings.CutPrefix(line, meter); ok {
-		e.msg.MeterId = after
-		return nil
+		var err error
+		if e.state == stateBlock {
+			err = e.discard("incomplete block, next block started")
+		}
+		e.state = stateBlock
+		e.started = now
+		e.msg = &model.EncryptedMessage{MeterId: strings.TrimSpace(after), Timestamp: ts}
+		e.lines = []string{line}
+		return nil, err
 	}
-	if after, ok := strings.CutPrefix(line, manufacturer); ok {
-		e.msg.Manufacturer = after
-		return nil
+
+	if e.state == stateBlock && now.Sub(e.started) > blockTimeout {
+		err := e.discard("stale block, no telegram within " + blockTimeout.String())
+		e.state = stateDiscarding
+		return nil, err
 	}
-	if after, ok := strings.CutPrefix(line, _type); ok {
-		e.msg.Type = after
-		return nil
+
+	after, isTelegram := strings.CutPrefix(line, telegramPrefix)
+	field, isField := blockField(line)
+	if !isTelegram && !isField {
+		util.Logger.Debug("ignored line with unhandled prefix", "line", line)
+		return nil, nil
 	}
-	if strings.HasPrefix(line, version) {
-		e.msg.Version = strings.TrimPrefix(line, version)
-		return nil
+	switch e.state {
+	case stateDiscarding:
+		// the rest of a block that was already reported
+		if isTelegram {
+			e.state = stateIdle
+		}
+		return nil, nil
+	case stateIdle:
+		if isTelegram {
+			return nil, &MalformedBlockError{Reason: "telegram outside of block", Lines: []string{line}}
+		}
+		e.state = stateDiscarding
+		return nil, &MalformedBlockError{Reason: "line outside of block", Lines: []string{line}}
 	}
-	if after, ok := strings.CutPrefix(line, rssi); ok {
-		rssi := after
-		parts := strings.Split(rssi, " ")
+	if len(e.lines) < maxBlockLines {
+		e.lines = append(e.lines, line)
+	}
+	if isField {
+		return nil, e.setField(field, strings.TrimPrefix(line, field))
+	}
+
+	// the telegram is always the last line
+	e.msg.Telegram, _, _ = strings.Cut(after, telegramSuffix)
+	msg := e.msg
+	if err := e.validate(); err != nil {
+		e.state = stateIdle
+		return nil, err
+	}
+	e.state = stateIdle
+	e.msg = nil
+	e.lines = nil
+	return msg, nil
+}
+
+func blockField(line string) (string, bool) {
+	for _, prefix := range []string{manufacturer, _type, version, rssi, driver, device} {
+		if strings.HasPrefix(line, prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+func (e *encryptedExtractor) setField(field string, value string) error {
+	switch field {
+	case manufacturer:
+		e.msg.Manufacturer = value
+	case _type:
+		e.msg.Type = value
+	case version:
+		e.msg.Version = value
+	case driver:
+		e.msg.Driver = value
+	case device:
+		e.msg.Device = value
+	case rssi:
+		parts := strings.Split(value, " ")
 		rssinum, err := strconv.ParseFloat(parts[0], 64)
 		if err != nil {
+			// the telegram is still usable without rssi
 			util.Logger.Warn("unable to parse rssi to float", "string", parts[0])
 			return nil
 		}
@@ -143,26 +301,41 @@ func (e *encryptedExtractor) handleLine(line string) *model.EncryptedMessage {
 		if len(parts) > 1 {
 			e.msg.RSSIUnit = parts[1]
 		}
-		return nil
 	}
-	if after, ok := strings.CutPrefix(line, driver); ok {
-		e.msg.Driver = after
-		return nil
+	return nil
+}
+
+// validate checks a complete block. Telegrams whose link or transport layer address does not match the block's
+// meter id were mixed up by interleaved output.
+func (e *encryptedExtractor) validate() error {
+	if e.msg.MeterId == "" {
+		return e.discard("missing meter id")
 	}
-	if after, ok := strings.CutPrefix(line, device); ok {
-		e.msg.Device = after
-		return nil
+	if e.msg.Telegram == "" {
+		return e.discard("empty telegram")
 	}
-	if after, ok := strings.CutPrefix(line, telegramPrefix); ok {
-		tg := after
-		parts := strings.Split(tg, telegramSuffix)
-		e.msg.Telegram = parts[0]
-
-		// the telegram is always the last line
-		p := e.msg
-		e.msg = nil
-		return p
+	b, err := telegram.ParseHex(e.msg.Telegram)
+	if err != nil {
+		return e.discard("telegram is not hex: " + err.Error())
+	}
+	ids, err := telegram.MeterIds(b)
+	if err != nil {
+		return e.discard("telegram too short")
+	}
+	if !slices.ContainsFunc(ids, func(id string) bool { return strings.EqualFold(id, e.msg.MeterId) }) {
+		return e.discard("telegram was sent by meter " + strings.Join(ids, "/"))
 	}
-	util.Logger.Info("ignored line with unhandled prefix", "line", line)
 	return nil
 }
+
+// discard drops the current block and returns the error describing it.
+func (e *encryptedExtractor) discard(reason string) error {
+	err := &MalformedBlockError{Reason: reason, Lines: e.lines}
+	if e.msg != nil {
+		err.MeterId = e.msg.MeterId
+	}
+	e.state = stateDiscarding
+	e.msg = nil
+	e.lines = nil
+	return err
+}
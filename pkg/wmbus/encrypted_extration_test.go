@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// line of a log block, wait is the time passed before it was written
+type logLine struct {
+	text string
+	wait time.Duration
+}
+
+// result of a line, the meter id of a completed message or the reason of a malformed block
+type lineResult struct {
+	meterId string
+	reason  string
+}
+
+func block(meterId string, telegram string) []logLine {
+	return []logLine{
+		{text: "Received telegram from: " + meterId},
+		{text: "          manufacturer: (KAM) Kamstrup Energi (0x2c2d)"},
+		{text: "                  type: Cold water meter (0x16)"},
+		{text: "                  rssi: -60 dBm"},
+		{text: "                driver: multical21"},
+		{text: "telegram=|_" + telegram + "|"},
+	}
+}
+
+const (
+	telegram12345678 = "2D442D2C785634121B16"
+	telegram87654321 = "2D442D2C214365871B16"
+)
+
+func TestEncryptedExtractor(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	a := block("12345678", telegram12345678)
+	b := block("87654321", telegram87654321)
+	tests := []struct {
+		name  string
+		lines []logLine
+		want  []lineResult // per line that completed a message or returned an error
+	}{
+		{"block", a, []lineResult{{meterId: "12345678"}}},
+		{"consecutive blocks", slices.Concat(a, b), []lineResult{{meterId: "12345678"}, {meterId: "87654321"}}},
+		{
+			name:  "interleaved blocks",
+			lines: slices.Concat(a[:3], b),
+			want:  []lineResult{{reason: "incomplete block, next block started"}, {meterId: "87654321"}},
+		},
+		{
+			name:  "stale block",
+			lines: slices.Concat(a[:2], []logLine{{text: a[2].text, wait: blockTimeout + time.Second}}, a[3:], b),
+			want:  []lineResult{{reason: "stale block"}, {meterId: "87654321"}},
+		},
+		{
+			name:  "slow block",
+			lines: slices.Concat(a[:5], []logLine{{text: a[5].text, wait: blockTimeout - time.Second}}),
+			want:  []lineResult{{meterId: "12345678"}},
+		},
+		{"telegram outside of block", slices.Concat(a[5:], b), []lineResult{{reason: "telegram outside of block"}, {meterId: "87654321"}}},
+		{"line outside of block", slices.Concat(a[1:], b), []lineResult{{reason: "line outside of block"}, {meterId: "87654321"}}},
+		{
+			name:  "meter id mismatch",
+			lines: slices.Concat(block("12345678", telegram87654321), a),
+			want:  []lineResult{{reason: "telegram was sent by meter 87654321"}, {meterId: "12345678"}},
+		},
+		{"telegram not hex", slices.Concat(block("12345678", "2D44ZZ"), b), []lineResult{{reason: "telegram is not hex"}, {meterId: "87654321"}}},
+		{"telegram too short", block("12345678", "2D44"), []lineResult{{reason: "telegram too short"}}},
+		{"unhandled lines", slices.Concat(a[:2], []logLine{{text: "(main) some informational message"}}, a[2:]), []lineResult{{meterId: "12345678"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			e := encryptedExtractor{now: func() time.Time { return now }}
+			got := []lineResult{}
+			for _, line := range tt.lines {
+				now = now.Add(line.wait)
+				msg, err := e.handleLine(line.text)
+				switch {
+				case err != nil:
+					var mbe *MalformedBlockError
+					if !errors.As(err, &mbe) {
+						t.Fatalf("unexpected error %v", err)
+					}
+					got = append(got, lineResult{reason: mbe.Reason})
+				case msg != nil:
+					got = append(got, lineResult{meterId: msg.MeterId})
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].meterId != tt.want[i].meterId || !strings.HasPrefix(got[i].reason, tt.want[i].reason) {
+					t.Errorf("got %+v, want %+v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptedExtractorMessage(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	e := encryptedExtractor{}
+	lines := block("12345678", telegram12345678)
+	lines[0].text = "[2024-03-01_12:00:05] " + lines[0].text
+	for i, line := range lines {
+		m, err := e.handleLine(line.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(lines)-1 {
+			if m != nil {
+				t.Fatalf("message completed by line %d", i)
+			}
+			continue
+		}
+		if m == nil {
+			t.Fatal("no message")
+		}
+		ts := time.Date(2024, 3, 1, 12, 0, 5, 0, time.Local)
+		if m.Timestamp == nil || !m.Timestamp.Equal(ts) {
+			t.Errorf("timestamp = %v, want %v", m.Timestamp, ts)
+		}
+		if m.Manufacturer != "(KAM) Kamstrup Energi (0x2c2d)" || m.Type != "Cold water meter (0x16)" || m.Driver != "multical21" {
+			t.Errorf("fields not set: %+v", m)
+		}
+		if m.RSSI != -60 || m.RSSIUnit != "dBm" || m.Telegram != telegram12345678 {
+			t.Errorf("rssi or telegram not set: %+v", m)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ensureFifo creates a fifo at path if nothing exists there and reports whether it did. The fifo is only
+// accessible by the service until its permissions are set.
+func ensureFifo(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return true, syscall.Mkfifo(path, 0600)
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return false, fmt.Errorf("%s is not a fifo", path)
+	}
+	return false, nil
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/model"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/telegram"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const (
+	readingFormatJson      = "json"
+	readingFormatFields    = "fields"
+	readingFormatHr        = "hr"
+	readingFormatTelegrams = "telegrams" // --logtelegrams output
+)
+
+const defaultSeparator = ";"
+
+// detectReadingFormat guesses the format of a meter reading file from one of its lines.
+func detectReadingFormat(line string) string {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "{"):
+		return readingFormatJson
+	case strings.HasPrefix(line, "telegram="):
+		return readingFormatTelegrams
+	case strings.Contains(line, "\t"):
+		return readingFormatHr
+	default:
+		return readingFormatFields
+	}
+}
+
+// readingFileReader reads the lines of a meter reading file. The format is detected from the first line.
+type readingFileReader struct {
+	source config.Source
+	file   string
+	format string
+	warned bool // missing fields were reported
+}
+
+// read parses a line into a telegram, or into a reading and its JSON encoding. All are nil for empty lines and
+// lines that can not be read, which are logged.
+func (r *readingFileReader) read(text string) (*model.EncryptedMessage, map[string]any, []byte) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil, nil
+	}
+	if r.format == "" {
+		r.format = detectReadingFormat(text)
+		util.Logger.Info("detected meter reading format", "file", r.file, "format", r.format)
+	}
+	switch r.format {
+	case readingFormatJson:
+		j := map[string]any{}
+		err := json.Unmarshal([]byte(text), &j)
+		if err != nil {
+			util.Logger.Error("unable to unmarshal meter reading line", "file", r.file, "line", text, "err", err)
+			return nil, nil, nil
+		}
+		return nil, j, []byte(text)
+	case readingFormatTelegrams:
+		msg, err := parseTelegramLine(text)
+		if err != nil {
+			util.Logger.Error("unable to read telegram line", "file", r.file, "line", text, "err", err)
+			return nil, nil, nil
+		}
+		return msg, nil, nil
+	}
+	if len(r.source.Fields) == 0 {
+		if !r.warned {
+			util.Logger.Error("unable to read meter reading file: fields of source not configured", "file", r.file, "format", r.format)
+			r.warned = true
+		}
+		return nil, nil, nil
+	}
+	j, err := parseFieldsLine(r.format, text, r.source)
+	if err != nil {
+		util.Logger.Error("unable to read meter reading line", "file", r.file, "format", r.format, "line", text, "err", err)
+		return nil, nil, nil
+	}
+	raw, err := json.Marshal(j)
+	if err != nil {
+		util.Logger.Error("unable to marshal meter reading", "file", r.file, "err", err)
+		return nil, nil, nil
+	}
+	return nil, j, raw
+}
+
+// parseFieldsLine turns a line of the fields or hr format into the fields of a JSON reading, named by the
+// configured field list, which must not be empty. Values of the hr format carry their unit, which is dropped as
+// it is part of the name.
+func parseFieldsLine(format string, line string, source config.Source) (map[string]any, error) {
+	sep := source.Separator
+	if sep == "" {
+		sep = defaultSeparator
+	}
+	if format == readingFormatHr {
+		sep = "\t"
+	}
+	values := strings.Split(line, sep)
+	if len(values) != len(source.Fields) {
+		return nil, fmt.Errorf("%d values, %d fields configured", len(values), len(source.Fields))
+	}
+	j := map[string]any{}
+	for i, field := range source.Fields {
+		value := strings.TrimSpace(values[i])
+		if format == readingFormatHr && field != "name" && field != "id" {
+			if number, _, ok := strings.Cut(value, " "); ok {
+				if _, err := strconv.ParseFloat(number, 64); err == nil {
+					value = number
+				}
+			}
+		}
+		j[field] = fieldValue(field, value)
+	}
+	return j, nil
+}
+
+func fieldValue(field string, value string) any {
+	if field == "id" || field == "name" {
+		return value
+	}
+	if field == "timestamp" {
+		// the text formats use local time, JSON readings RFC3339
+		if t, err := time.ParseInLocation(time.DateTime, value, time.Local); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+		return value
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// parseTelegramLine reads a line of --logtelegrams, e.g. telegram=|2E44...|+43, into a message
+// addressed by the meter of the telegram, see telegram.Telegram.MeterId.
+func parseTelegramLine(line string) (*model.EncryptedMessage, error) {
+	after, ok := strings.CutPrefix(strings.TrimSpace(line), "telegram=|")
+	if !ok {
+		return nil, errors.New("missing telegram prefix")
+	}
+	hexTelegram, _, _ := strings.Cut(after, telegramSuffix)
+	hexTelegram = strings.TrimPrefix(hexTelegram, "_")
+	b, err := telegram.ParseHex(hexTelegram)
+	if err != nil {
+		return nil, err
+	}
+	t, err := telegram.Decode(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	// long transport layer headers address the meter, the link layer may be that of a repeater
+	manufacturer, media, deviceType, version := t.Link.Manufacturer, t.Link.Media, t.Link.DeviceType, t.Link.Version
+	if tpl := t.Transport; tpl != nil && tpl.Header == "long" {
+		manufacturer, media, deviceType, version = tpl.Manufacturer, tpl.Media, tpl.DeviceType, tpl.Version
+	}
+	return &model.EncryptedMessage{
+		Telegram:     hexTelegram,
+		Manufacturer: manufacturer,
+		MeterId:      t.MeterId(),
+		Type:         fmt.Sprintf("%s%s meter (0x%02x)", strings.ToUpper(media[:1]), media[1:], deviceType), // as in the log
+		Version:      fmt.Sprintf("0x%02x", version),
+	}, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"io"
+	"testing"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+func TestReadingFileReader(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	fields := config.Source{Fields: []string{"name", "id", "total_m3", "timestamp"}}
+	tests := []struct {
+		name    string
+		source  config.Source
+		lines   []string
+		want    []string // meter id per read reading or telegram
+		encrypt bool
+	}{
+		{"json", config.Source{}, []string{`{"id":"12345678","name":"m","total_m3":1.5}`, `{broken`}, []string{"12345678"}, false},
+		{"fields", fields, []string{"m;12345678;1.5;2024-03-01 12:00:00", "m;87654321;1.5", ""}, []string{"12345678"}, false},
+		{"hr", fields, []string{"m\t12345678\t1.5 m3\t2024-03-01 12:00:00"}, []string{"12345678"}, false},
+		{"fields without configured fields", config.Source{}, []string{"m;12345678;1.5", "m;12345678;1.6"}, nil, false},
+		{"telegrams", config.Source{}, []string{"telegram=|2E44_2D2C785634121B168D2091D37CAC21E1D68CDAFFCD3DC452BD802913FF7B1706CA9E355D6C2701CC24|+43"}, []string{"12345678"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &readingFileReader{source: tt.source, file: tt.name}
+			var got []string
+			for _, line := range tt.lines {
+				msg, j, _ := r.read(line)
+				switch {
+				case msg != nil:
+					if !tt.encrypt {
+						t.Errorf("unexpected telegram of %s", msg.MeterId)
+					}
+					got = append(got, msg.MeterId)
+				case j != nil:
+					got = append(got, stringField(j, "id"))
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("read %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("read %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTelegramLine(t *testing.T) {
+	msg, err := parseTelegramLine("telegram=|2E44_2D2C785634121B168D2091D37CAC21E1D68CDAFFCD3DC452BD802913FF7B1706CA9E355D6C2701CC24|+43")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MeterId != "12345678" || msg.Manufacturer != "KAM" || msg.Type != "Cold water meter (0x16)" || msg.Version != "0x1b" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestIsReadingLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{`{"id":"12345678"}`, true},
+		{"telegram=|2E44...|+43", true},
+		{"m\t12345678\t1.5 m3", true},
+		{"m;12345678;1.5;2024-03-01 12:00:00", true},
+		{"[2024-03-01_12:00:00] Received telegram from: 12345678", false},
+		{"          manufacturer: (KAM) Kamstrup Energi (0x2c2d)", false},
+		{"(wmbus) started config files; listening", false},
+	}
+	for _, tt := range tests {
+		if got := isReadingLine(tt.line, config.Source{}); got != tt.want {
+			t.Errorf("isReadingLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
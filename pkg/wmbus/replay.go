@@ -0,0 +1,307 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/discovery"
+	nimbusmgw "github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/nimbus_mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+// ReplayOptions select the messages of replayed files and how fast they are forwarded.
+type ReplayOptions struct {
+	Radio    string    // radio that received the files, the first configured radio if empty
+	From     time.Time // messages before are skipped, no lower bound if zero
+	To       time.Time // messages after are skipped, no upper bound if zero
+	Meters   []string  // all meters if empty
+	Realtime bool      // keep the time between messages instead of forwarding as fast as possible
+}
+
+// ReplayStats counts the messages of a replay.
+type ReplayStats struct {
+	Forwarded int
+	Skipped   int // not selected by the replay options
+	Dropped   int // selected, but denied by the filter or invalid
+}
+
+// Replay feeds wmbusmeters log files and meter reading files through the pipeline into s. Meter reading files
+// in fields or hr format are read with the fields of the radio's meter reading source. Directories are
+// replayed file by file in name order. Devices are passed to announce instead of the device manager and liveness
+// is not changed. With discovery enabled, only defined meters and those approved in the checkpoint file are
+// forwarded, nothing is recorded for discovery. Diagnostics of historical lines are not sent and messages
+// without timestamp are dropped, as their time of reception is unknown.
+func Replay(ctx context.Context, cfg *config.Config, s sink.Sink, announce func(nimbusmgw.Device) error, files []string, opts ReplayOptions) (ReplayStats, error) {
+	radio, ok := replayRadio(cfg, opts.Radio)
+	if !ok {
+		return ReplayStats{}, fmt.Errorf("unknown radio %q", opts.Radio)
+	}
+	var disc *discovery.Discovery
+	if cfg.Pipeline.Discovery.Enabled {
+		store, err := checkpoint.Load(cfg.CheckpointFile)
+		if err != nil {
+			return ReplayStats{}, fmt.Errorf("unable to load discovery decisions: %w", err)
+		}
+		disc = discovery.New(store)
+	}
+	devices := &replayDevices{devices: map[string]nimbusmgw.Device{}, announce: announce}
+	w, err := newPipeline(config.NewReloader("", cfg), s, devices, disc)
+	if err != nil {
+		return ReplayStats{}, err
+	}
+	w.replay = true
+	devices.AddIdempotent(radioDevice(radio))
+	r := &replayer{w: w, radio: radio, source: replaySource(radio), opts: opts, ctx: ctx}
+	for _, file := range files {
+		err = r.replayPath(file)
+		if err != nil {
+			return r.stats, err
+		}
+	}
+	return r.stats, ctx.Err()
+}
+
+func replayRadio(cfg *config.Config, id string) (config.Radio, bool) {
+	for _, radio := range cfg.Radios {
+		if id == "" || radio.Id == id {
+			return radio, true
+		}
+	}
+	return config.Radio{}, false
+}
+
+// replaySource returns the meter reading source of a radio, whose fields are used to read files in fields or
+// hr format.
+func replaySource(radio config.Radio) config.Source {
+	for _, source := range radio.Sources {
+		if source.Type == config.SourceTypeMeterReadings {
+			return source
+		}
+	}
+	return config.Source{}
+}
+
+type replayer struct {
+	w         *WmbusLogForwarder
+	radio     config.Radio
+	source    config.Source
+	opts      ReplayOptions
+	ctx       context.Context
+	extractor encryptedExtractor
+	detected  bool               // whether the current file is a log or a meter reading file
+	reader    *readingFileReader // nil for log files
+	last      time.Time
+	stats     ReplayStats
+}
+
+func (r *replayer) replayPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return r.replayFile(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		err = r.replayFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *replayer) replayFile(file string) error {
+	util.Logger.Info("replaying file", "file", file)
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// log blocks do not span files
+	r.extractor = encryptedExtractor{}
+	r.detected, r.reader = false, nil
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if r.ctx.Err() != nil {
+			return nil
+		}
+		r.handleLine(file, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// handleLine passes the lines of meter reading files to the meter reading or telegram handler and all other
+// lines to the log extractor. The kind of file is detected from its first line.
+func (r *replayer) handleLine(file string, text string) {
+	if !r.detected && strings.TrimSpace(text) != "" {
+		r.detected = true
+		if isReadingLine(text, r.source) {
+			r.reader = &readingFileReader{source: r.source, file: file}
+		}
+	}
+	if r.reader == nil {
+		msg := r.w.handleLogLine(r.radio, file, &r.extractor, text)
+		if msg == nil || !r.selected(msg.MeterId, msg.Timestamp) {
+			return
+		}
+		r.count(r.w.handleEncryptedMessage(r.radio, msg))
+		return
+	}
+	msg, j, raw := r.reader.read(text)
+	switch {
+	case msg != nil:
+		if r.selected(msg.MeterId, msg.Timestamp) {
+			r.count(r.w.handleEncryptedMessage(r.radio, msg))
+		}
+	case j != nil:
+		var ts *time.Time
+		if t, err := time.Parse(time.RFC3339, stringField(j, "timestamp")); err == nil {
+			ts = &t
+		}
+		if r.selected(stringField(j, "id"), ts) {
+			r.count(r.w.handleMeterReading(r.radio, file, j, raw))
+		}
+	}
+}
+
+// count counts a selected message by whether the pipeline forwarded it.
+func (r *replayer) count(forwarded bool) {
+	if forwarded {
+		r.stats.Forwarded++
+	} else {
+		r.stats.Dropped++
+	}
+}
+
+// isReadingLine reports whether a line is a meter reading in one of the formats of detectReadingFormat rather
+// than wmbusmeters log output. Log lines never contain tabs, start with a timestamp or component in brackets,
+// e.g. (wmbus), or name their values with ": ".
+func isReadingLine(line string, source config.Source) bool {
+	switch detectReadingFormat(line) {
+	case readingFormatJson, readingFormatTelegrams, readingFormatHr:
+		return true
+	}
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "(") || strings.HasPrefix(line, "[") || strings.Contains(line, ": ") {
+		return false
+	}
+	return strings.Contains(line, cmp.Or(source.Separator, defaultSeparator))
+}
+
+// selected reports whether a message passes the replay options and, for realtime replays, waits until it is due.
+// Messages without timestamp are skipped if a time range is set.
+func (r *replayer) selected(meterId string, ts *time.Time) bool {
+	ok := len(r.opts.Meters) == 0 || slices.Contains(r.opts.Meters, meterId)
+	if ok && (!r.opts.From.IsZero() || !r.opts.To.IsZero()) {
+		ok = ts != nil && !ts.Before(r.opts.From) && (r.opts.To.IsZero() || !ts.After(r.opts.To))
+	}
+	if !ok {
+		r.stats.Skipped++
+		return false
+	}
+	if r.opts.Realtime && ts != nil {
+		if !r.last.IsZero() && ts.After(r.last) {
+			select {
+			case <-r.ctx.Done():
+			case <-time.After(ts.Sub(r.last)):
+			}
+		}
+		r.last = *ts
+	}
+	return true
+}
+
+// replayDevices keeps the devices seen during a replay in memory.
+type replayDevices struct {
+	devices  map[string]nimbusmgw.Device
+	announce func(nimbusmgw.Device) error
+	mux      sync.Mutex
+}
+
+func (d *replayDevices) AddIdempotent(device *nimbusmgw.Device) {
+	d.mux.Lock()
+	_, ok := d.devices[device.Id]
+	d.mux.Unlock()
+	if !ok {
+		d.Update(device)
+	}
+}
+
+func (d *replayDevices) Get(id string) (nimbusmgw.Device, bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	device, ok := d.devices[id]
+	return device, ok
+}
+
+func (d *replayDevices) Update(device *nimbusmgw.Device) {
+	d.mux.Lock()
+	d.devices[device.Id] = *device
+	d.mux.Unlock()
+	if d.announce == nil {
+		return
+	}
+	err := d.announce(*device)
+	if err != nil {
+		util.Logger.Error("unable to announce device", "device_id", device.Id, "err", err)
+	}
+}
+
+func (d *replayDevices) List() []nimbusmgw.Device {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	devices := make([]nimbusmgw.Device, 0, len(d.devices))
+	for _, device := range d.devices {
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+func (d *replayDevices) Remove(id string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.devices, id)
+}
+
+// Touch is a no-op, replayed telegrams say nothing about the current state of a meter.
+func (d *replayDevices) Touch(string, string) {}
+
+func (d *replayDevices) ObserveClock(_ string, meterTime time.Time, receivedAt time.Time) time.Duration {
+	return meterTime.Sub(receivedAt)
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+)
+
+const replayLog = `[2024-03-01_12:00:00] (wmbus) WARNING! decrypted content failed check, did you use the correct decryption key? Permanently ignoring telegrams from id: 12345678 mfct: (KAM) Kamstrup
+[2024-03-01_12:00:00] Received telegram from: 12345678
+          manufacturer: (KAM) Kamstrup Energi (0x2c2d)
+                  type: Cold water meter (0x16)
+                   ver: 0x1b
+                  rssi: -60 dBm
+                driver: multical21
+telegram=|_2D442D2C785634121B16|
+[2024-03-01_12:00:02] Received telegram from: 87654321
+          manufacturer: (KAM) Kamstrup Energi (0x2c2d)
+                  type: Cold water meter (0x16)
+                   ver: 0x1b
+                  rssi: -70 dBm
+                driver: multical21
+telegram=|_2D442D2C214365871B16|
+`
+
+const replayReadings = `{"id":"12345678","name":"kitchen","total_m3":1.5,"timestamp":"2024-03-01T12:00:00Z"}
+{"id":"12345678","name":"kitchen","total_m3":1.6}
+{"id":"87654321","name":"bath","total_m3":2.5,"timestamp":"2024-03-01T12:00:00Z"}
+{"id":"../../12345678","name":"kitchen","total_m3":1.7,"timestamp":"2024-03-01T12:00:00Z"}
+`
+
+func TestReplay(t *testing.T) {
+	util.InitStructLoggerTo("error", io.Discard)
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	logFile := write("wmbus.log", replayLog)
+	readingsFile := write("readings.jsonl", replayReadings)
+	write("checkpoints.json", `{"discovery_decisions": {"12345678": "approved", "87654321": "rejected"}}`)
+	cfg, err := config.New(write("config.json", `{
+		"checkpoint_file": "`+filepath.Join(dir, "checkpoints.json")+`",
+		"radios": [{"id": "r1", "sources": [{"type": "log", "path": "/x"}]}],
+		"pipeline": {"discovery": {"enabled": true}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		discover bool
+		want     ReplayStats
+		events   []string // service id per event
+	}{
+		{"log", logFile, true, ReplayStats{Forwarded: 1, Dropped: 1}, []string{encryptedServiceId}},
+		{"log without discovery", logFile, false, ReplayStats{Forwarded: 2}, []string{encryptedServiceId, encryptedServiceId}},
+		{"readings", readingsFile, true, ReplayStats{Forwarded: 1, Dropped: 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *cfg
+			c.Pipeline.Discovery.Enabled = tt.discover
+			buf := &bytes.Buffer{}
+			stats, err := Replay(context.Background(), &c, sink.NewWriter(buf), nil, []string{tt.file}, ReplayOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats != tt.want {
+				t.Errorf("stats = %+v, want %+v", stats, tt.want)
+			}
+			events := []string{}
+			scanner := bufio.NewScanner(buf)
+			for scanner.Scan() {
+				e := struct {
+					ServiceId string `json:"service_id"`
+				}{}
+				if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+					t.Fatal(err)
+				}
+				if e.ServiceId == diagnosticsServiceId {
+					t.Errorf("diagnostic sent for replayed line: %s", scanner.Text())
+				}
+				events = append(events, e.ServiceId)
+			}
+			if tt.events != nil && !slices.Equal(events, tt.events) {
+				t.Errorf("events = %v, want %v", events, tt.events)
+			}
+		})
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "checkpoints.json")); !bytes.Contains(data, []byte(`"87654321": "rejected"`)) {
+		t.Errorf("checkpoint file modified: %s", data)
+	}
+}
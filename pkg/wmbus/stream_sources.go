@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// handleJsonStream reads the --format=json output of wmbusmeters from stdin, if path is -, or from the fifo
+// at path. A missing fifo is created with the mode and group of the source, an existing one is left as it is.
+func (w *WmbusLogForwarder) handleJsonStream(radio config.Radio, source config.Source) {
+	path := source.Path
+	var r io.ReadCloser = os.Stdin
+	if path != "-" {
+		created, err := ensureFifo(path)
+		if err == nil && created {
+			err = setPermissions(path, source)
+		}
+		if err != nil {
+			util.Logger.Error("unable to create fifo", "path", path, "err", err)
+			w.cf()
+			return
+		}
+		// opened for writing too, so that the fifo stays open when wmbusmeters restarts
+		r, err = os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			util.Logger.Error("unable to open fifo", "path", path, "err", err)
+			w.cf()
+			return
+		}
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		<-w.ctx.Done()
+		_ = r.Close()
+	}()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		err := w.readMeterReadings(radio, path, r)
+		if err != nil && w.ctx.Err() == nil {
+			util.Logger.Error("unable to read json stream", "path", path, "err", err)
+			return
+		}
+		if w.ctx.Err() == nil {
+			util.Logger.Warn("json stream closed", "path", path)
+		}
+	}()
+}
+
+// handleShellSocket accepts readings from the hook subcommand, which wmbusmeters runs with shell=. Connections
+// are closed on shutdown.
+func (w *WmbusLogForwarder) handleShellSocket(radio config.Radio, source config.Source) {
+	path := source.Path
+	// remove the socket of a previous run
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		util.Logger.Error("unable to remove stale socket", "path", path, "err", err)
+		w.cf()
+		return
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		util.Logger.Error("unable to listen for shell hook", "path", path, "err", err)
+		w.cf()
+		return
+	}
+	err = setPermissions(path, source)
+	if err != nil {
+		util.Logger.Error("unable to set shell hook socket permissions", "path", path, "err", err)
+		_ = l.Close()
+		w.cf()
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		<-w.ctx.Done()
+		_ = l.Close()
+	}()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if w.ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					util.Logger.Error("unable to accept shell hook connection", "path", path, "err", err)
+				}
+				return
+			}
+			w.wg.Add(1)
+			go func() {
+				defer w.wg.Done()
+				stop := context.AfterFunc(w.ctx, func() {
+					_ = conn.Close()
+				})
+				defer stop()
+				defer conn.Close()
+				err := w.readMeterReadings(radio, path, conn)
+				if err != nil && w.ctx.Err() == nil {
+					util.Logger.Warn("unable to read from shell hook", "path", path, "err", err)
+				}
+			}()
+		}
+	}()
+}
+
+// setPermissions applies the mode and group of a source to its socket or fifo.
+func setPermissions(path string, source config.Source) error {
+	mode, err := source.FileMode()
+	if err != nil {
+		return err
+	}
+	if source.Group != "" {
+		gid, err := strconv.Atoi(source.Group)
+		if err != nil {
+			group, err := user.LookupGroup(source.Group)
+			if err != nil {
+				return err
+			}
+			gid, err = strconv.Atoi(group.Gid)
+			if err != nil {
+				return err
+			}
+		}
+		err = os.Chown(path, -1, gid)
+		if err != nil {
+			return err
+		}
+	}
+	return os.Chmod(path, mode)
+}
+
+// handleMqttSource subscribes to the readings wmbusmeters publishes, one JSON reading per message. The client id
+// contains the index of the source, so that sources of the same radio do not take over each other's connection.
+func (w *WmbusLogForwarder) handleMqttSource(radio config.Radio, index int, source config.Source) {
+	broker := source.Broker
+	if broker == "" {
+		broker = w.cfg().MqttConnStr
+	}
+	topic := source.Path
+	options := paho.NewClientOptions().
+		AddBroker(broker).
+		SetClientID("mgw-wmbus-dc-" + radio.Id + "-readings-" + strconv.Itoa(index)).
+		SetAutoReconnect(true).
+		SetCleanSession(true).
+		SetOnConnectHandler(func(c paho.Client) {
+			token := c.Subscribe(topic, 1, func(_ paho.Client, msg paho.Message) {
+				w.handleMeterReadingText(radio, msg.Topic(), strings.TrimSpace(string(msg.Payload())))
+			})
+			if token.Wait() && token.Error() != nil {
+				util.Logger.Error("unable to subscribe to wmbusmeters readings", "broker", broker, "topic", topic, "err", token.Error())
+				return
+			}
+			util.Logger.Info("subscribed to wmbusmeters readings", "broker", broker, "topic", topic)
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			util.Logger.Warn("connection to wmbusmeters broker lost", "broker", broker, "err", err)
+		})
+	client := paho.NewClient(options)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		util.Logger.Error("unable to connect to wmbusmeters broker", "broker", broker, "err", token.Error())
+		w.cf()
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		<-w.ctx.Done()
+		client.Disconnect(250)
+	}()
+}
+
+// readMeterReadings forwards every line of r as a JSON reading until r is closed.
+func (w *WmbusLogForwarder) readMeterReadings(radio config.Radio, source string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		w.handleMeterReadingText(radio, source, text)
+	}
+	return scanner.Err()
+}
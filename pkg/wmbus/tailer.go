@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wmbus
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/logrotate"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+	"github.com/nxadm/tail"
+)
+
+const seekBucket = "seek"
+
+// tailFile follows file starting at its last checkpoint and calls handle for every complete line.
+// The checkpoint is advanced after handle returns. If file was rotated since the checkpoint, the unread
+// parts of its rotated copies are replayed first.
+func (w *WmbusLogForwarder) tailFile(file string, handle func(text string)) {
+	// add log rotation, since not done by wmbusmeters
+	w.logRotater.AddFiles(file)
+
+	seekinfo, unread, err := w.resumePosition(file)
+	if err != nil {
+		util.Logger.Error("unable to determine resume position", "file", file, "err", err)
+		w.cf()
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for _, seg := range unread {
+			util.Logger.Info("replaying unread part of rotated file", "file", file, "rotated_file", seg.File, "offset", seg.Offset)
+			err := logrotate.ReadSegment(seg, handle)
+			if err != nil {
+				util.Logger.Error("unable to replay rotated file", "file", seg.File, "err", err)
+			}
+			if w.ctx.Err() != nil {
+				return
+			}
+		}
+		t, err := tail.TailFile(file, tail.Config{
+			Follow:        true,
+			Logger:        tail.DiscardingLogger,
+			ReOpen:        true,
+			CompleteLines: true,
+			Location:      seekinfo,
+		})
+		if err != nil {
+			util.Logger.Error("Error tailing file", "file", file, "error", err)
+			w.cf()
+			return
+		}
+		defer t.Cleanup()
+
+		var id logrotate.FileId
+		var lastOffset int64
+		for {
+			select {
+			case line := <-t.Lines:
+				if line == nil {
+					continue
+				}
+				if line.Err != nil {
+					util.Logger.Warn("error while tailing file", "file", file, "err", line.Err)
+					continue
+				}
+				handle(line.Text)
+
+				// re-identify after the tailer reopened or truncated the file and until the fingerprint is complete
+				if id.Fingerprint == "" || line.SeekInfo.Offset < lastOffset || (id.FingerprintLen < logrotate.FingerprintSize && line.SeekInfo.Offset > id.FingerprintLen) {
+					id, err = logrotate.Identify(file)
+					if err != nil {
+						util.Logger.Warn("unable to identify file", "file", file, "err", err)
+					}
+				}
+				lastOffset = line.SeekInfo.Offset
+				err = w.checkpoints.Set(seekBucket, file, logrotate.Checkpoint{
+					SeekInfo: line.SeekInfo,
+					FileId:   id,
+					Time:     time.Now(),
+				})
+				if err != nil {
+					util.Logger.Error("unable to store seek info", "file", file, "err", err)
+				}
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// resumePosition returns where to continue reading file. Reading restarts at the beginning of the file if
+// it was rotated since the last checkpoint, be it by copytruncate, rename or recreation. In that case the
+// unread segments of the rotated copies are returned as well.
+func (w *WmbusLogForwarder) resumePosition(file string) (*tail.SeekInfo, []logrotate.Segment, error) {
+	cp := w.loadCheckpoint(file)
+	if cp == nil {
+		return nil, nil, nil
+	}
+	rotation, moved, err := logrotate.Detect(file, *cp, w.cfg().LogBackupDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rotation == logrotate.NotRotated {
+		return &cp.SeekInfo, nil, nil
+	}
+	util.Logger.Info("file was rotated since last checkpoint, reading from start", "file", file, "rotation", rotation, "moved_to", moved)
+	if cp.Time.IsZero() {
+		// migrated seek files carry no identity, rotated copies cannot be matched
+		return nil, nil, nil
+	}
+	unread, err := logrotate.FindUnread(file, *cp, moved, w.cfg().LogBackupDir)
+	if err != nil {
+		util.Logger.Error("unable to search rotated files for unread data", "file", file, "err", err)
+		return nil, nil, nil
+	}
+	return nil, unread, nil
+}
+
+// loadCheckpoint returns the checkpoint of file. If the checkpoint store has none,
+// the seek file written by older versions to SeekDir is used instead.
+func (w *WmbusLogForwarder) loadCheckpoint(file string) *logrotate.Checkpoint {
+	var cp logrotate.Checkpoint
+	ok, err := w.checkpoints.Get(seekBucket, file, &cp)
+	if err != nil {
+		util.Logger.Error("unable to read seek info", "file", file, "err", err)
+		return nil
+	}
+	if ok {
+		return &cp
+	}
+	if w.cfg().SeekDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(w.cfg().SeekDir, filepath.Base(file)))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	err = json.Unmarshal(data, &cp.SeekInfo)
+	if err != nil {
+		util.Logger.Warn("unable to unmarshal legacy seek file", "file", file, "err", err)
+		return nil
+	}
+	util.Logger.Info("migrated legacy seek file", "file", file, "offset", cp.Offset)
+	return &cp
+}
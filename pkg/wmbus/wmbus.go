@@ -17,70 +17,368 @@
 package wmbus
 
 import (
+	"cmp"
 	"context"
-	"os"
-
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/aggregate"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/alarm"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/archive"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/checkpoint"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/derived"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/discovery"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/filter"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/logrotate"
 	nimbusmgw "github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/nimbus_mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/plausibility"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/schema"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
 	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
-	"github.com/nxadm/tail"
 )
 
 const (
-	decryptedServiceId = "decrypted"
-	encryptedServiceId = "encrypted"
+	decryptedServiceId    = "decrypted"
+	encryptedServiceId    = "encrypted"
+	measurementsServiceId = "measurements"
+	derivedServiceId      = "derived"
+	alertsServiceId       = "alerts"
+	diagnosticsServiceId  = "diagnostics"
 )
 
+// Devices keeps the devices of radios and meters. *nimbusmgw.DeviceManager satisfies this interface.
+type Devices interface {
+	AddIdempotent(d *nimbusmgw.Device)
+	Get(id string) (nimbusmgw.Device, bool)
+	Update(d *nimbusmgw.Device)
+	List() []nimbusmgw.Device
+	Remove(id string)
+	Touch(id string, source string)
+	ObserveClock(id string, meterTime time.Time, receivedAt time.Time) time.Duration
+}
+
 type WmbusLogForwarder struct {
-	cfg           *config.Config
-	mgwClient     *mgw.Client[nimbusmgw.Device]
-	deviceManager *nimbusmgw.DeviceManager
+	reloader      *config.Reloader
+	sink          sink.Sink
+	deviceManager Devices
 	logRotater    *logrotate.LogRotator
+	checkpoints   *checkpoint.Store
+	filter        atomic.Pointer[filter.Filter]
+	schemas       atomic.Pointer[schema.Registry]
+	schemaDrift   sync.Map // reported drifts
+	meters        *checkpoint.Bucket[meterInfo]
+	metersMux     sync.Mutex // serializes updates of meters
+	plausibility  *plausibility.Checker
+	alarms        *alarm.Tracker
+	derived       *derived.Calculator
+	location      atomic.Pointer[time.Location] // of derived daily and monthly totals
+	aggregator    *aggregate.Aggregator         // readings are sent right away if nil
+	archive       *archive.Archive              // readings are not archived if nil
+	discovery     *discovery.Discovery
+	diagnostics   diagnostics
+	replay        bool // historical messages are fed by Replay
 	ctx           context.Context
 	cf            context.CancelFunc
 	wg            *sync.WaitGroup
 }
 
-func NewLogForwarder(cfg *config.Config, mgwClient *mgw.Client[nimbusmgw.Device], deviceManager *nimbusmgw.DeviceManager, ctx context.Context, cf context.CancelFunc, wg *sync.WaitGroup) {
-	logRotater := logrotate.NewLogRotator(ctx, wg, logrotate.LogRotatorConfig{
-		BackupDir: cfg.LogBackupDir,
-		Backups:   2,
-	})
-	err := os.MkdirAll(cfg.SeekDir, 0744)
+// NewLogForwarder starts forwarding the sources of all radios until ctx is done. It returns nil if the pipeline
+// could not be created, cf is called then.
+func NewLogForwarder(reloader *config.Reloader, sink sink.Sink, deviceManager Devices, checkpoints *checkpoint.Store, discovery *discovery.Discovery, ctx context.Context, cf context.CancelFunc, wg *sync.WaitGroup) *WmbusLogForwarder {
+	cfg := reloader.Get()
+	w, err := newPipeline(reloader, sink, deviceManager, discovery)
 	if err != nil {
-		util.Logger.Error("unable to create seek dir", "dir", cfg.SeekDir, "err", err)
+		util.Logger.Error("unable to create pipeline", "err", err)
 		cf()
-		return
+		return nil
+	}
+	w.logRotater = logrotate.NewLogRotator(ctx, wg, logrotate.LogRotatorConfig{
+		BackupDir: cfg.LogBackupDir,
+		Backups:   cfg.LogBackups,
+	})
+	w.checkpoints = checkpoints
+	w.meters = checkpoint.NewBucket[meterInfo](checkpoints, metersBucket)
+	w.plausibility = plausibility.New(checkpoints)
+	w.derived = derived.New(checkpoints)
+	w.alarms = alarm.New(checkpoints)
+	w.aggregator = aggregate.New(ctx, wg, func(b aggregate.Batch) {
+		if b.Telegram != nil {
+			w.sendEncrypted(b.Radio, b.Telegram)
+			return
+		}
+		w.send(b.MeterId, b.Payload, b.Reading)
+	})
+	if cfg.ArchiveDir != "" {
+		w.archive, err = archive.New(cfg.ArchiveDir, cfg.ArchiveDays, ctx, wg)
+		if err != nil {
+			util.Logger.Error("unable to create archive", "dir", cfg.ArchiveDir, "err", err)
+			cf()
+			return nil
+		}
+	}
+	w.ctx = ctx
+	w.cf = cf
+	w.wg = wg
+	reloader.OnChange(w.applyMeterChanges)
+	reloader.OnChange(func(old *config.Config, new *config.Config) {
+		if reflect.DeepEqual(old.Pipeline.Filter, new.Pipeline.Filter) {
+			return
+		}
+		err := w.setFilter(new.Pipeline.Filter)
+		if err != nil {
+			util.Logger.Error("unable to update filter, keeping current filter", "err", err)
+			return
+		}
+		w.reconcileDevices()
+	})
+	reloader.OnChange(func(old *config.Config, new *config.Config) {
+		if reflect.DeepEqual(old.Pipeline.Schemas, new.Pipeline.Schemas) {
+			return
+		}
+		err := w.setSchemas(new.Pipeline.Schemas)
+		if err != nil {
+			util.Logger.Error("unable to update schemas, keeping current schemas", "err", err)
+		}
+	})
+	reloader.OnChange(func(old *config.Config, new *config.Config) {
+		if old.Pipeline.Derived.Timezone == new.Pipeline.Derived.Timezone {
+			return
+		}
+		err := w.setLocation(new.Pipeline.Derived.Timezone)
+		if err != nil {
+			util.Logger.Error("unable to update timezone, keeping current timezone", "err", err)
+		}
+	})
+	w.reconcileDevices()
+	for _, radio := range cfg.Radios {
+		for i, source := range radio.Sources {
+			switch source.Type {
+			case config.SourceTypeLog:
+				w.handleWmbusmetersLogFile(radio, source.Path)
+			case config.SourceTypeMeterReadings:
+				w.handleWmbusmetersMeterReadingDirectory(radio, source)
+			case config.SourceTypeJsonStream:
+				w.handleJsonStream(radio, source)
+			case config.SourceTypeShell:
+				w.handleShellSocket(radio, source)
+			case config.SourceTypeMqtt:
+				w.handleMqttSource(radio, i, source)
+			}
+		}
+	}
+	return w
+}
+
+// Close sends the pending aggregated readings and telegrams. It has to be called after the sources stopped
+// and before the sinks are closed.
+func (w *WmbusLogForwarder) Close() {
+	w.aggregator.Flush()
+}
+
+// aggregation returns the aggregation policy of a meter.
+func aggregation(cfg *config.Config, meterId string) config.Aggregation {
+	if m, ok := cfg.Meter(meterId); ok && m.Aggregation != nil {
+		return *m.Aggregation
 	}
+	return cfg.Pipeline.Aggregation
+}
+
+// newPipeline creates a forwarder that handles messages without watching any source. Discovery is
+// skipped if discovery is nil.
+func newPipeline(reloader *config.Reloader, sink sink.Sink, deviceManager Devices, discovery *discovery.Discovery) (*WmbusLogForwarder, error) {
 	w := &WmbusLogForwarder{
-		cfg:           cfg,
-		mgwClient:     mgwClient,
+		reloader:      reloader,
+		sink:          sink,
 		deviceManager: deviceManager,
-		logRotater:    logRotater,
-		ctx:           ctx,
-		cf:            cf,
-		wg:            wg,
+		discovery:     discovery,
+		meters:        checkpoint.NewBucket[meterInfo](nil, metersBucket),
+		plausibility:  plausibility.New(nil),
+		derived:       derived.New(nil),
+		alarms:        alarm.New(nil),
+	}
+	err := w.setLocation(reloader.Get().Pipeline.Derived.Timezone)
+	if err != nil {
+		return nil, err
 	}
-	w.handleWmbusmetersLogFile()
-	w.handleWmbusmetersMeterReadingDirectory()
+	err = w.setSchemas(reloader.Get().Pipeline.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	return w, w.setFilter(reloader.Get().Pipeline.Filter)
 }
 
-// Validates that the seekinfo offset is within the file's size. Returns nil seekinfo if file is smaller than
-// the offset, indicating file rotation. This causes reading from the start of the file.
-func ensureSeekInfoInBounds(file string, seekinfo *tail.SeekInfo) (*tail.SeekInfo, error) {
-	if seekinfo == nil {
-		return nil, nil
+func (w *WmbusLogForwarder) setSchemas(schemas []config.Schema) error {
+	r, err := schema.New(schemas)
+	if err != nil {
+		return err
 	}
-	info, err := os.Stat(file)
-	if err != nil && !os.IsNotExist(err) {
-		return nil, err
+	w.schemas.Store(r)
+	return nil
+}
+
+// setLocation sets the timezone of derived totals, local time if timezone is empty.
+func (w *WmbusLogForwarder) setLocation(timezone string) error {
+	if timezone == "" {
+		w.location.Store(time.Local)
+		return nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return err
+	}
+	w.location.Store(loc)
+	return nil
+}
+
+func radioDevice(radio config.Radio) *nimbusmgw.Device {
+	return &nimbusmgw.Device{
+		Id:           radio.Id,
+		Name:         radio.Name,
+		DeviceTypeId: radio.DeviceTypeId,
+	}
+}
+
+func (w *WmbusLogForwarder) cfg() *config.Config {
+	return w.reloader.Get()
+}
+
+func (w *WmbusLogForwarder) setFilter(cfg config.Filter) error {
+	f, err := filter.New(cfg)
+	if err != nil {
+		return err
+	}
+	w.filter.Store(f)
+	return nil
+}
+
+const metersBucket = "meters"
+
+// meterInfo is what was last seen of a meter, e.g. to filter its decrypted readings, which lack manufacturer and type.
+type meterInfo struct {
+	Name         string `json:"name,omitempty"`         // as reported by wmbusmeters
+	Driver       string `json:"driver,omitempty"`       // wmbusmeters driver
+	Manufacturer string `json:"manufacturer,omitempty"` // see filter.Manufacturer
+	Type         string `json:"type,omitempty"`         // see filter.Type
+}
+
+// observeMeter updates what is known of a meter. It is only stored if it changed.
+func (w *WmbusLogForwarder) observeMeter(id string, update func(info *meterInfo)) meterInfo {
+	w.metersMux.Lock()
+	defer w.metersMux.Unlock()
+	info, _ := w.meters.Get(id)
+	next := info
+	update(&next)
+	if next != info {
+		w.meters.Set(id, next)
+	}
+	return next
+}
+
+// admit reports whether an observed meter may be forwarded. Meters have to pass the filter and,
+// if discovery is enabled, be defined or approved.
+func (w *WmbusLogForwarder) admit(o discovery.Observation) bool {
+	ok, reason := w.filter.Load().Allowed(o.Subject)
+	if !ok {
+		util.Logger.Debug("filtered meter", "meter_id", o.MeterId, "reason", reason)
+		return false
+	}
+	cfg := w.cfg()
+	if !cfg.Pipeline.Discovery.Enabled || w.discovery == nil {
+		return true
+	}
+	if _, ok := cfg.Meter(o.MeterId); ok {
+		return true
+	}
+	if w.replay {
+		return w.discovery.Approved(o.MeterId)
+	}
+	return w.discovery.Admit(o)
+}
+
+// reconcileDevices applies the current configuration to the known devices, including those restored from the
+// checkpoint store. Radios and meters get their configured name and device type, meters that would no longer
+// be admitted are removed.
+func (w *WmbusLogForwarder) reconcileDevices() {
+	cfg := w.cfg()
+	for _, radio := range cfg.Radios {
+		w.deviceManager.Update(radioDevice(radio))
+	}
+	for _, d := range w.deviceManager.List() {
+		if slices.ContainsFunc(cfg.Radios, func(r config.Radio) bool { return r.Id == d.Id }) {
+			continue
+		}
+		info, _ := w.meters.Get(d.Id)
+		subject := filter.Subject{MeterId: d.Id, Manufacturer: info.Manufacturer, Type: info.Type, Driver: info.Driver}
+		if !w.admitted(subject) {
+			util.Logger.Info("meter is no longer admitted, removing device", "device_id", d.Id)
+			w.deviceManager.Remove(d.Id)
+			continue
+		}
+		w.deviceManager.Update(w.meterDevice(d.Id, d.Name))
+	}
+}
+
+// admitted reports whether a known meter would still be admitted. Unlike admit, it records nothing for discovery.
+func (w *WmbusLogForwarder) admitted(s filter.Subject) bool {
+	if ok, _ := w.filter.Load().Allowed(s); !ok {
+		return false
+	}
+	cfg := w.cfg()
+	if !cfg.Pipeline.Discovery.Enabled || w.discovery == nil {
+		return true
+	}
+	if _, ok := cfg.Meter(s.MeterId); ok {
+		return true
+	}
+	return w.discovery.Approved(s.MeterId)
+}
+
+// applyMeterChanges updates the devices of meters whose definition was added, changed or removed.
+// Annotations are not part of the device, they are resolved per reading and apply to the following readings.
+func (w *WmbusLogForwarder) applyMeterChanges(old *config.Config, new *config.Config) {
+	ids := []string{}
+	for _, m := range append(slices.Clone(old.Meters), new.Meters...) {
+		if !slices.Contains(ids, m.Id) {
+			ids = append(ids, m.Id)
+		}
+	}
+	for _, id := range ids {
+		o, _ := old.Meter(id)
+		n, _ := new.Meter(id)
+		if !reflect.DeepEqual(o.Annotation(), n.Annotation()) {
+			util.Logger.Info("meter annotation changed, applied to following readings", "meter_id", id)
+		}
+		if o.Name == n.Name && o.DeviceTypeId == n.DeviceTypeId {
+			continue
+		}
+		if _, ok := w.deviceManager.Get(id); !ok {
+			continue
+		}
+		w.deviceManager.Update(w.meterDevice(id, ""))
+	}
+}
+
+// meterDevice returns the device of a meter, preferring the name and device type of its meter definition.
+// Without defined name, the name wmbusmeters last reported for the meter is used, then fallback and the id.
+func (w *WmbusLogForwarder) meterDevice(id string, fallback string) *nimbusmgw.Device {
+	cfg := w.cfg()
+	info, _ := w.meters.Get(id)
+	d := &nimbusmgw.Device{
+		Id:           id,
+		Name:         cmp.Or(info.Name, fallback, id),
+		DeviceTypeId: cfg.Pipeline.MeterDeviceTypeId,
 	}
-	if info.Size() < seekinfo.Offset {
-		return nil, nil
+	if m, ok := cfg.Meter(id); ok {
+		if m.Name != "" {
+			d.Name = m.Name
+		}
+		if m.DeviceTypeId != "" {
+			d.DeviceTypeId = m.DeviceTypeId
+		}
 	}
-	return seekinfo, nil
+	return d
 }
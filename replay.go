@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/configuration"
+	"github.com/SENERGY-Platform/mgw-dc-lib-go/pkg/mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/config"
+	nimbusmgw "github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/nimbus_mgw"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/sink"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/util"
+	"github.com/SENERGY-Platform/mgw-wmbus-dc/pkg/wmbus"
+)
+
+var replayTimeLayouts = []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly}
+
+// replay feeds historical wmbusmeters logs and meter readings through the pipeline and returns the exit code.
+func replay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "usage: mgw-wmbus-dc replay [flags] file|dir...")
+		flags.PrintDefaults()
+	}
+	confPath := flags.String("config", "", "path to config JSON file")
+	radio := flags.String("radio", "", "id of the radio that received the files, defaults to the first radio")
+	from := flags.String("from", "", "skip messages received before, RFC3339 or local date and time")
+	to := flags.String("to", "", "skip messages received after, RFC3339 or local date and time")
+	meters := flags.String("meters", "", "comma separated ids of the meters to replay, all if empty")
+	speed := flags.String("speed", "fast", "fast to replay as fast as possible, realtime to keep the time between messages")
+	dryRun := flags.Bool("dry-run", false, "write events to stdout instead of the configured sinks")
+	_ = flags.Parse(args)
+
+	fail := func(err error) int {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	opts := wmbus.ReplayOptions{Radio: *radio}
+	var err error
+	if opts.From, err = parseReplayTime(*from); err != nil {
+		return fail(fmt.Errorf("from: %w", err))
+	}
+	if opts.To, err = parseReplayTime(*to); err != nil {
+		return fail(fmt.Errorf("to: %w", err))
+	}
+	if *meters != "" {
+		opts.Meters = strings.Split(*meters, ",")
+	}
+	switch *speed {
+	case "fast":
+	case "realtime":
+		opts.Realtime = true
+	default:
+		return fail(fmt.Errorf("speed: unknown speed %q, expected fast or realtime", *speed))
+	}
+
+	cfg, err := config.New(*confPath)
+	if err != nil {
+		return fail(err)
+	}
+	// stdout is reserved for events
+	util.InitStructLoggerTo(cfg.LogLevel, os.Stderr)
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+	go func() {
+		util.Wait(ctx, util.Logger, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		cf()
+	}()
+
+	var s sink.Sink = sink.NewWriter(os.Stdout)
+	if !*dryRun {
+		// devices belong to the running connector, the replay only publishes events and must not take over its
+		// mqtt client id
+		mgwClient, err := mgw.New[nimbusmgw.Device](configuration.Config{
+			ConnectorId:   "mgw-wmbus-dc-replay",
+			MgwMqttBroker: cfg.MqttConnStr,
+		}, ctx, wg, nil)
+		if err != nil {
+			return fail(fmt.Errorf("unable to create mgw client: %w", err))
+		}
+		s, err = sink.New(cfg.Sinks, mgwClient, os.Stdout)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	stats, err := wmbus.Replay(ctx, cfg, s, nil, flags.Args(), opts)
+	util.Logger.Info("replay finished", "forwarded", stats.Forwarded, "skipped", stats.Skipped, "dropped", stats.Dropped)
+	if err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func parseReplayTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range replayTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time %q", s)
+}